@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"sync"
+	"time"
 )
 
 const (
@@ -13,6 +14,7 @@ const (
 
 type Auth struct {
 	Trusted map[string]*Endpoint `json:"trusted"`
+	Policy  *Policy              `json:"-"`
 	Mutex   sync.Mutex
 }
 
@@ -45,21 +47,186 @@ func (na *Auth) RemoveTrusted(ip string) error {
 	return nil
 }
 
+// SetPolicy
+// Assigns the attribute-based policy evaluated after an endpoint's Permission bitmap has
+// granted access to a method, a nil policy disables the stage.
+func (na *Auth) SetPolicy(policy *Policy) {
+	na.Mutex.Lock()
+	na.Policy = policy
+	na.Mutex.Unlock()
+}
+
 func (na *Auth) IsEndpointAuthorized(sender *Address, request Request, path string, method HTTPMethod) bool {
-	validFlag := false // by default, we will assume that the ip doesn't exist in the hash map
-	if endpoint, ok := na.Trusted[sender.GetHost()]; ok {
-		// 1. does the user have permission to send an HTTP method request to the current path
-		// 2. does the message come from a user with the same ECDSA key pair
-		validFlag = endpoint.HasPermissionToUseMethod(path, method) && endpoint.ValidateSource(request)
+	return na.Authorize(sender, request, path, method).Authorized
+}
+
+// Authorize
+// Runs every stage of authorization and returns the Decision of the first stage that
+// rejected the request, or an authorized Decision describing the rules that granted it.
+func (na *Auth) Authorize(sender *Address, request Request, path string, method HTTPMethod) Decision {
+	decision, _ := na.AuthorizeEndpoint(sender, request, path, method)
+	return decision
+}
+
+// AuthorizeEndpoint
+// Identical to Authorize, the Endpoint whose signature was verified is also returned when the
+// request is authorized, nil otherwise.
+func (na *Auth) AuthorizeEndpoint(sender *Address, request Request, path string, method HTTPMethod) (Decision, *Endpoint) {
+	decision := Decision{Path: path, Method: method.ToString()}
+
+	// by default, we will assume that the ip doesn't exist in the hash map
+	na.Mutex.Lock()
+	endpoint, ok := na.Trusted[sender.GetHost()]
+	policy := na.Policy
+	na.Mutex.Unlock()
+
+	if !ok {
+		decision.Reason = ReasonUnknownSource
+		return decision, nil
+	}
+	decision.Endpoint = endpoint.Name
+
+	// 1. does the user have permission to send an HTTP method request to the current path
+	if !na.permits(endpoint, &decision) {
+		return decision, nil
+	}
+
+	// 2. does the message come from a user with the same ECDSA key pair, the nonce is recorded
+	// under the same lock it is checked under so that the signature cannot be replayed
+	na.Mutex.Lock()
+	reason := endpoint.VerifySource(request)
+	if reason == ReasonAuthorized {
+		endpoint.LastNonce = request.GetNonce()
+	}
+	na.Mutex.Unlock()
+
+	if reason != ReasonAuthorized {
+		decision.Reason = reason
+		return decision, nil
+	}
+
+	// 3. do the policy rules allow the endpoint to use the method given the request attributes
+	na.evaluate(policy, PolicyContext{
+		Endpoint: endpoint,
+		Path:     path,
+		Method:   method,
+		Source:   sender,
+		Time:     time.Now(),
+		Params:   request.GetParams(),
+	}, &decision)
+
+	if !decision.Authorized {
+		return decision, nil
+	}
+	return decision, endpoint
+}
+
+// Explain
+// A dry-run of Authorize for the endpoint registered under the name (or host) given. The
+// signature and nonce are not evaluated as there is no request to check them against, every
+// other stage is applied as it would be at the current time.
+func (na *Auth) Explain(endpoint string, path string, method HTTPMethod) Decision {
+	decision := Decision{Path: path, Method: method.ToString(), DryRun: true}
+
+	na.Mutex.Lock()
+	host, record, ok := na.lookup(endpoint)
+	policy := na.Policy
+	na.Mutex.Unlock()
+
+	if !ok {
+		decision.Reason = ReasonUnknownSource
+		return decision
+	}
+	decision.Endpoint = record.Name
+
+	if !na.permits(record, &decision) {
+		return decision
+	}
+
+	source := EmptyAddress()
+	source.Host = host
+
+	na.evaluate(policy, PolicyContext{
+		Endpoint: record,
+		Path:     path,
+		Method:   method,
+		Source:   source,
+		Time:     time.Now(),
+	}, &decision)
+
+	return decision
+}
+
+// Lookup
+// Finds the endpoint registered under the name (or host) given, and the host it is trusted under.
+func (na *Auth) Lookup(endpoint string) (string, *Endpoint, bool) {
+	na.Mutex.Lock()
+	defer na.Mutex.Unlock()
+
+	return na.lookup(endpoint)
+}
+
+// lookup
+// Finds an endpoint by the host it is trusted under or by its name, the caller must hold the mutex.
+func (na *Auth) lookup(endpoint string) (string, *Endpoint, bool) {
+	if record, ok := na.Trusted[endpoint]; ok {
+		return endpoint, record, true
+	}
+	for host, record := range na.Trusted {
+		if record.Name == endpoint {
+			return host, record, true
+		}
+	}
+	return EmptyString, nil, false
+}
+
+func (na *Auth) permits(endpoint *Endpoint, decision *Decision) bool {
+	method := HTTPMethodFromString(decision.Method)
+
+	if permission, ok := endpoint.LocalPermissions[decision.Path]; ok {
+		decision.Rule = "local permission " + decision.Path + " " + permission.String()
+	} else if endpoint.GlobalPermissions != nil {
+		decision.Rule = "global permission " + endpoint.GlobalPermissions.String()
+	} else {
+		decision.Rule = "no local or global permission"
+	}
+
+	if !endpoint.HasPermissionToUseMethod(decision.Path, method) {
+		decision.Reason = ReasonMethodNotPermitted
+		return false
+	}
+	return true
+}
+
+func (na *Auth) evaluate(policy *Policy, context PolicyContext, decision *Decision) {
+	if policy == nil {
+		decision.Authorized = true
+		decision.Reason = ReasonAuthorized
+		return
+	}
+
+	// when no policy rule matched and the default allows the request, the permission
+	// bitmap is still the rule that decided the outcome
+	allowed, rule := policy.Evaluate(context)
+	if rule != nil {
+		decision.Rule = "policy " + rule.String()
+	} else if !allowed {
+		decision.Rule = "policy default " + policy.Default.ToString()
+	}
+
+	decision.Authorized = allowed
+	if allowed {
+		decision.Reason = ReasonAuthorized
+	} else {
+		decision.Reason = ReasonPolicyDenied
 	}
-	return validFlag
 }
 
 func Sign(request Request, key *ecdsa.PrivateKey) error {
 	// if the nonce has never been created, generate one
 	var nonce int64
 	if request.GetNonce() == MissingNonceValue {
-		nonce = GenerateNonce() // int64 -> currentTime * random int
+		nonce = GenerateNonce() // int64 -> increasing with the current time
 	} else {
 		// the Node will verify that the nonce is greater than the previous, otherwise
 		// we risk allowing a threat actor to re-send the same nonce and signature again
@@ -70,7 +237,7 @@ func Sign(request Request, key *ecdsa.PrivateKey) error {
 	hash := request.GetHash()
 	signature, err := ecdsa.SignASN1(rand.Reader, key, hash)
 	if err != nil {
-		errors.New("there was an error signing the request data")
+		return errors.New("there was an error signing the request data")
 	}
 	request.SetSignature(signature)
 
@@ -0,0 +1,7 @@
+//go:build production
+
+package fack
+
+// ProductionBuild is true when the binary is compiled with the production build tag
+// (go build -tags production); development bypass policies can never be enabled.
+const ProductionBuild = true
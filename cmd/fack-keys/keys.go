@@ -0,0 +1,359 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	privateKeyBlock = "EC PRIVATE KEY"
+	publicKeyBlock  = "PUBLIC KEY"
+)
+
+func generate(args []string) error {
+	flags := newFlagSet("generate")
+	out := flags.String("out", "fack.pem", "private key file, the public key is written beside it as <name>.pub.pem")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privateBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	publicBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	publicOut := strings.TrimSuffix(*out, ".pem") + ".pub.pem"
+
+	// the private key should only ever be readable by its owner
+	if err := writePEM(*out, privateKeyBlock, privateBytes, 0600); err != nil {
+		return err
+	}
+	if err := writePEM(publicOut, publicKeyBlock, publicBytes, 0644); err != nil {
+		return err
+	}
+
+	fingerprint, _ := fack.NewEndpoint(fack.EmptyString, &key.PublicKey).Fingerprint()
+	fmt.Printf("private key: %s\npublic key:  %s\nfingerprint: %s\n", *out, publicOut, fingerprint)
+
+	return nil
+}
+
+func fingerprint(args []string) error {
+	flags := newFlagSet("fingerprint")
+	file := flags.String("key", "", "public or private key PEM file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	publicKey, err := readPublicKey(*file)
+	if err != nil {
+		return err
+	}
+
+	fingerprint, ok := fack.NewEndpoint(fack.EmptyString, publicKey).Fingerprint()
+	if !ok {
+		return errors.New("could not encode the public key")
+	}
+	fmt.Println(fingerprint)
+
+	return nil
+}
+
+func endpoint(args []string) error {
+	flags := newFlagSet("endpoint")
+	file := flags.String("key", "", "public or private key PEM file")
+	name := flags.String("name", "", "name of the endpoint")
+	global := flags.String("global", "", "global permission as a list of methods (ex. GET,POST), all or none")
+	roles := flags.String("roles", "", "comma separated roles evaluated by policies")
+	local := list{}
+	flags.Var(&local, "local", "local permission as <route>=<methods> (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if len(*name) == 0 {
+		return errors.New("an endpoint requires a -name")
+	}
+
+	publicKey, err := readPublicKey(*file)
+	if err != nil {
+		return err
+	}
+	data, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	record := fack.NewEndpoint(*name, nil)
+	record.GeneratePublicKey(data)
+
+	if len(*global) > 0 {
+		permission, err := parsePermission(*global)
+		if err != nil {
+			return err
+		}
+		record.AddGlobalPermission(permission)
+	}
+
+	for _, entry := range local {
+		split := strings.SplitN(entry, "=", 2)
+		if len(split) != 2 {
+			return fmt.Errorf("local permission %q is not in the form <route>=<methods>", entry)
+		}
+		permission, err := parsePermission(split[1])
+		if err != nil {
+			return err
+		}
+		if !record.AddLocalPermission(split[0], permission) {
+			return fmt.Errorf("local permission for %s was given more than once", split[0])
+		}
+	}
+
+	if len(*roles) > 0 {
+		for _, role := range strings.Split(*roles, ",") {
+			record.AddRole(role)
+		}
+	}
+
+	// the record is loaded through the x509 string, the parsed key is not exported
+	record.PublicKey = nil
+
+	return printJSON(record)
+}
+
+func sign(args []string) error {
+	flags := newFlagSet("sign")
+	file := flags.String("key", "", "private key PEM file")
+	function := flags.String("function", "/", "function the request is sent to")
+	signer := flags.String("signer", "", "co-sign as the named endpoint instead of signing as the sender")
+	path, method := coSignatureFlags(flags)
+	nonce := flags.Int64("nonce", fack.MissingNonceValue, "nonce to increment from, a new nonce is generated when omitted")
+	params := list{}
+	flags.Var(&params, "param", "request parameter (repeatable)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	key, err := readPrivateKey(*file)
+	if err != nil {
+		return err
+	}
+
+	route, httpMethod, err := coSignatureRoute(*function, *path, *method)
+	if err != nil {
+		return err
+	}
+
+	request := rpc.NewRequest(*function)
+	request.Param = params
+	request.SetNonce(*nonce)
+
+	if len(*signer) > 0 {
+		err = fack.CoSign(request, *signer, key, route, httpMethod)
+	} else {
+		err = fack.Sign(request, key)
+	}
+	if err != nil {
+		return err
+	}
+
+	return printJSON(request)
+}
+
+func verify(args []string) error {
+	flags := newFlagSet("verify")
+	file := flags.String("key", "", "public or private key PEM file")
+	input := flags.String("request", "-", "captured request JSON file, - reads from stdin")
+	signer := flags.String("signer", "", "verify the co-signature of the named endpoint instead of the sender signature")
+	path, method := coSignatureFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	publicKey, err := readPublicKey(*file)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if *input == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(*input)
+	}
+	if err != nil {
+		return err
+	}
+
+	request := rpc.NewRequest(fack.EmptyString)
+	if err := json.Unmarshal(data, request); err != nil {
+		return err
+	}
+
+	reason := fack.ReasonSignatureMismatch
+	if len(*signer) > 0 {
+		route, httpMethod, err := coSignatureRoute(request.Function, *path, *method)
+		if err != nil {
+			return err
+		}
+
+		for _, signature := range request.GetSignatures() {
+			if signature.Endpoint != *signer {
+				continue
+			}
+			hash := fack.CoSignatureHash(request, signature.Nonce, route, httpMethod)
+			if ecdsa.VerifyASN1(publicKey, hash, signature.Signature) {
+				reason = fack.ReasonAuthorized
+			}
+		}
+	} else {
+		reason = fack.NewEndpoint(fack.EmptyString, publicKey).VerifySource(request)
+	}
+
+	fmt.Printf("function: %s\nnonce:    %d\nresult:   %s\n", request.Function, request.GetNonce(), reason.ToString())
+	if reason != fack.ReasonAuthorized {
+		return errors.New("the request was not signed by the key")
+	}
+
+	return nil
+}
+
+// coSignatureFlags
+// A co-signature is bound to the path of the route and the method it is sent with.
+func coSignatureFlags(flags *flag.FlagSet) (*string, *string) {
+	path := flags.String("path", "", "route path a co-signature is bound to, defaults to the function")
+	method := flags.String("method", "POST", "HTTP method a co-signature is bound to")
+	return path, method
+}
+
+func coSignatureRoute(function, path, method string) (string, fack.HTTPMethod, error) {
+	method = strings.ToUpper(method)
+	if !fack.IsValidHTTPMethod(method) {
+		return "", 0, fmt.Errorf("%q is not one of GET, POST, PULL or DELETE", method)
+	}
+	if len(path) == 0 {
+		path = function
+	}
+	return path, fack.HTTPMethodFromString(method), nil
+}
+
+func parsePermission(methods string) (*fack.Permission, error) {
+	switch methods {
+	case "all":
+		return fack.NewPermission().FullAccess(), nil
+	case "none":
+		return fack.NewPermission().NoAccess(), nil
+	}
+
+	permission := fack.NewPermission()
+	for _, method := range strings.Split(methods, ",") {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if !fack.IsValidHTTPMethod(method) {
+			return nil, fmt.Errorf("%q is not one of GET, POST, PULL or DELETE", method)
+		}
+		permission.Enable(fack.HTTPMethodFromString(method))
+	}
+
+	return permission, nil
+}
+
+func writePEM(file, blockType string, data []byte, mode os.FileMode) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return pem.Encode(f, &pem.Block{Type: blockType, Bytes: data})
+}
+
+func readBlock(file string) (*pem.Block, error) {
+	if len(file) == 0 {
+		return nil, errors.New("a -key file is required")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", file)
+	}
+
+	return block, nil
+}
+
+func readPrivateKey(file string) (*ecdsa.PrivateKey, error) {
+	block, err := readBlock(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type != privateKeyBlock {
+		return nil, fmt.Errorf("%s holds a %s, expected an %s", file, block.Type, privateKeyBlock)
+	}
+
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// readPublicKey
+// Accepts either half of a keypair, the public key is derived from a private key.
+func readPublicKey(file string) (*ecdsa.PublicKey, error) {
+	block, err := readBlock(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case privateKeyBlock:
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	case publicKeyBlock:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s does not hold an ECDSA public key", file)
+		}
+		return publicKey, nil
+	}
+
+	return nil, fmt.Errorf("%s holds an unsupported %s block", file, block.Type)
+}
+
+func printJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/GabeCordo/fack/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// capture
+// Runs a command with its standard output redirected to a file, returning what it printed.
+func capture(t *testing.T, run func() error) (string, error) {
+	file, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	stdout := os.Stdout
+	os.Stdout = file
+	err = run()
+	os.Stdout = stdout
+
+	data, _ := os.ReadFile(file.Name())
+	return string(data), err
+}
+
+// keypair
+// Generates a keypair in a temporary directory and returns the private and public key files.
+func keypair(t *testing.T, name string) (string, string) {
+	out := filepath.Join(t.TempDir(), name+".pem")
+	if _, err := capture(t, func() error { return generate([]string{"-out", out}) }); err != nil {
+		t.Fatal(err)
+	}
+	return out, strings.TrimSuffix(out, ".pem") + ".pub.pem"
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestGenerateKeypair(t *testing.T) {
+	private, public := keypair(t, "service")
+
+	info, err := os.Stat(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("private key was readable by others: %v", info.Mode())
+	}
+
+	key, err := readPrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := readPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PublicKey.Equal(publicKey) {
+		t.Error("public key file did not hold the public half of the private key")
+	}
+
+	derived, err := readPublicKey(private)
+	if (err != nil) || !derived.Equal(publicKey) {
+		t.Error("public key was not derived from the private key file")
+	}
+
+	// an existing key is never overwritten
+	if _, err := capture(t, func() error { return generate([]string{"-out", private}) }); err == nil {
+		t.Error("generate overwrote an existing private key")
+	}
+
+	printed, err := capture(t, func() error { return fingerprint([]string{"-key", private}) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := capture(t, func() error { return fingerprint([]string{"-key", public}) }); (len(printed) == 0) || (printed != other) {
+		t.Error("fingerprint of the private and public key files did not match")
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	private, public := keypair(t, "service")
+	_, otherPublic := keypair(t, "other")
+
+	signed, err := capture(t, func() error {
+		return sign([]string{"-key", private, "-function", "/deploy", "-param", "prod"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	captured := writeFile(t, "request.json", signed)
+
+	if _, err := capture(t, func() error { return verify([]string{"-key", public, "-request", captured}) }); err != nil {
+		t.Errorf("signed request did not verify: %s", err)
+	}
+	if _, err := capture(t, func() error { return verify([]string{"-key", otherPublic, "-request", captured}) }); err == nil {
+		t.Error("request verified against a key that did not sign it")
+	}
+
+	request := rpc.NewRequest("")
+	if err := json.Unmarshal([]byte(signed), request); err != nil {
+		t.Fatal(err)
+	}
+	request.Param = []string{"staging"}
+	tampered, _ := json.Marshal(request)
+	if _, err := capture(t, func() error {
+		return verify([]string{"-key", public, "-request", writeFile(t, "tampered.json", string(tampered))})
+	}); err == nil {
+		t.Error("request with altered params still verified")
+	}
+}
+
+func TestCoSignAndVerify(t *testing.T) {
+	private, public := keypair(t, "approver")
+
+	signed, err := capture(t, func() error {
+		return sign([]string{"-key", private, "-function", "/deploy", "-signer", "approver", "-method", "post"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	captured := writeFile(t, "request.json", signed)
+
+	if _, err := capture(t, func() error {
+		return verify([]string{"-key", public, "-request", captured, "-signer", "approver"})
+	}); err != nil {
+		t.Errorf("co-signature did not verify: %s", err)
+	}
+	if _, err := capture(t, func() error {
+		return verify([]string{"-key", public, "-request", captured, "-signer", "approver", "-path", "/rollback"})
+	}); err == nil {
+		t.Error("co-signature verified for a path it was not bound to")
+	}
+	if _, err := capture(t, func() error {
+		return verify([]string{"-key", public, "-request", captured, "-signer", "approver", "-method", "DELETE"})
+	}); err == nil {
+		t.Error("co-signature verified for a method it was not bound to")
+	}
+	if _, err := capture(t, func() error {
+		return verify([]string{"-key", public, "-request", captured, "-signer", "someone"})
+	}); err == nil {
+		t.Error("co-signature verified for a signer that did not sign")
+	}
+	if _, err := capture(t, func() error {
+		return sign([]string{"-key", private, "-signer", "approver", "-method", "BREW"})
+	}); err == nil {
+		t.Error("co-signature was bound to a method that does not exist")
+	}
+}
+
+func TestBadKeyFiles(t *testing.T) {
+	private, public := keypair(t, "service")
+
+	der, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaBytes, _ := x509.MarshalPKIXPublicKey(&der.PublicKey)
+
+	encode := func(blockType string, data []byte) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}))
+	}
+
+	cases := []struct {
+		name string
+		run  func() error
+	}{
+		{"missing key flag", func() error { return fingerprint(nil) }},
+		{"missing key file", func() error { return fingerprint([]string{"-key", filepath.Join(t.TempDir(), "absent.pem")}) }},
+		{"file without a PEM block", func() error {
+			return fingerprint([]string{"-key", writeFile(t, "key.pem", "not a key")})
+		}},
+		{"unsupported block type", func() error {
+			return fingerprint([]string{"-key", writeFile(t, "key.pem", encode("CERTIFICATE", []byte{1, 2, 3}))})
+		}},
+		{"corrupt private key", func() error {
+			return fingerprint([]string{"-key", writeFile(t, "key.pem", encode(privateKeyBlock, []byte{1, 2, 3}))})
+		}},
+		{"corrupt public key", func() error {
+			return fingerprint([]string{"-key", writeFile(t, "key.pub.pem", encode(publicKeyBlock, []byte{1, 2, 3}))})
+		}},
+		{"public key that is not ECDSA", func() error {
+			return fingerprint([]string{"-key", writeFile(t, "rsa.pub.pem", encode(publicKeyBlock, rsaBytes))})
+		}},
+		{"signing with a public key", func() error { return sign([]string{"-key", public}) }},
+		{"malformed request file", func() error {
+			return verify([]string{"-key", public, "-request", writeFile(t, "request.json", "{")})
+		}},
+		{"missing request file", func() error {
+			return verify([]string{"-key", public, "-request", filepath.Join(t.TempDir(), "absent.json")})
+		}},
+		{"unknown flag", func() error { return sign([]string{"-key", private, "-unknown"}) }},
+	}
+
+	for _, c := range cases {
+		if _, err := capture(t, c.run); err == nil {
+			t.Errorf("%s: bad input was accepted", c.name)
+		}
+	}
+}
+
+func TestEndpointRecord(t *testing.T) {
+	_, public := keypair(t, "service")
+
+	printed, err := capture(t, func() error {
+		return endpoint([]string{"-key", public, "-name", "service", "-global", "GET", "-local", "/deploy=GET,POST", "-roles", "deployer"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(printed), &record); err != nil {
+		t.Fatalf("endpoint record was not valid JSON: %s", err)
+	}
+	if record["name"] != "service" {
+		t.Errorf("endpoint record did not carry its name: %v", record)
+	}
+
+	for name, args := range map[string][]string{
+		"missing name":         {"-key", public},
+		"unknown method":       {"-key", public, "-name", "service", "-global", "BREW"},
+		"malformed local":      {"-key", public, "-name", "service", "-local", "/deploy"},
+		"duplicate local":      {"-key", public, "-name", "service", "-local", "/deploy=GET", "-local", "/deploy=POST"},
+		"missing key for name": {"-name", "service"},
+	} {
+		if _, err := capture(t, func() error { return endpoint(args) }); err == nil {
+			t.Errorf("%s: bad input was accepted", name)
+		}
+	}
+}
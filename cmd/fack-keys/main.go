@@ -0,0 +1,77 @@
+// fack-keys generates and inspects the ECDSA keys used to authenticate fack requests.
+//
+//	fack-keys generate    -out service.pem
+//	fack-keys fingerprint -key service.pub.pem
+//	fack-keys endpoint    -key service.pub.pem -name service -global GET -local /deploy=GET,POST
+//	fack-keys sign        -key service.pem -function /deploy -param prod > request.json
+//	fack-keys verify      -key service.pub.pem -request request.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands = []command{
+	{"generate", "generate a P-256 keypair and write it as PEM files", generate},
+	{"fingerprint", "print the SHA-256 fingerprint of a public or private key", fingerprint},
+	{"endpoint", "export an endpoint record with global and local permissions", endpoint},
+	{"sign", "sign a sample request for debugging", sign},
+	{"verify", "verify a captured request against a public key", verify},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+
+		if err := c.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "fack-keys %s: %s\n", c.name, err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: fack-keys <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.description)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "run fack-keys <command> -h for the flags of a command")
+}
+
+// list is a repeatable string flag (ex. -local /a=GET -local /b=POST)
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("fack-keys "+name, flag.ContinueOnError)
+}
@@ -0,0 +1,75 @@
+package fack
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type DecisionReason uint8
+
+const (
+	ReasonAuthorized DecisionReason = iota
+	ReasonUnknownSource
+	ReasonMissingPublicKey
+	ReasonStaleNonce
+	ReasonSignatureMismatch
+	ReasonMethodNotPermitted
+	ReasonPolicyDenied
+	ReasonInsufficientSignatures
+	ReasonDevelopmentBypass
+	ReasonPathMismatch
+	ReasonRouteNotFound
+)
+
+func (reason DecisionReason) ToString() string {
+	switch reason {
+	case ReasonAuthorized:
+		return "authorized"
+	case ReasonUnknownSource:
+		return "unknown_source"
+	case ReasonMissingPublicKey:
+		return "missing_public_key"
+	case ReasonStaleNonce:
+		return "stale_nonce"
+	case ReasonSignatureMismatch:
+		return "signature_mismatch"
+	case ReasonMethodNotPermitted:
+		return "method_not_permitted"
+	case ReasonPolicyDenied:
+		return "policy_denied"
+	case ReasonInsufficientSignatures:
+		return "insufficient_signatures"
+	case ReasonPathMismatch:
+		return "path_mismatch"
+	case ReasonRouteNotFound:
+		return "route_not_found"
+	case ReasonDevelopmentBypass:
+		return "development_bypass"
+	default:
+		return "unknown"
+	}
+}
+
+func (reason DecisionReason) MarshalJSON() ([]byte, error) {
+	return json.Marshal(reason.ToString())
+}
+
+// Decision
+// The outcome of an authorization check. Reason identifies the stage that decided the outcome
+// and Rule describes what matched at that stage: the Permission bitmap that granted or denied the
+// method, or the Policy rule that was applied.
+type Decision struct {
+	Authorized bool           `json:"authorized"`
+	Reason     DecisionReason `json:"reason"`
+	Endpoint   string         `json:"endpoint,omitempty"`
+	Path       string         `json:"path"`
+	Method     string         `json:"method"`
+	Rule       string         `json:"rule,omitempty"`
+	DryRun     bool           `json:"dryRun,omitempty"`
+}
+
+func (decision Decision) String() string {
+	template := "Decision[%t, %s, endpoint=%s, %s %s, rule=%s]"
+	return fmt.Sprintf(template, decision.Authorized, decision.Reason.ToString(),
+		decision.Endpoint, decision.Method, decision.Path, decision.Rule)
+}
@@ -0,0 +1,89 @@
+package fack
+
+import (
+	"net"
+	"strings"
+)
+
+type Environment uint8
+
+const (
+	Development Environment = iota
+	Production
+)
+
+func (environment Environment) ToString() string {
+	if environment == Production {
+		return "Production"
+	}
+	return "Development"
+}
+
+// DevelopmentPolicy
+// An explicit allow-list of sources that may skip authentication on a Node running in a
+// Development environment. A source is either a host ("127.0.0.1", "localhost") or a CIDR
+// block ("10.0.0.0/8"). The policy is ignored when the binary is built with the production
+// tag or when the Node is configured for the Production environment.
+type DevelopmentPolicy struct {
+	sources []string
+}
+
+func NewDevelopmentPolicy(sources ...string) *DevelopmentPolicy {
+	policy := new(DevelopmentPolicy)
+
+	for _, source := range sources {
+		policy.AllowSource(source)
+	}
+
+	return policy
+}
+
+func (policy *DevelopmentPolicy) AllowSource(source string) *DevelopmentPolicy {
+	if len(source) == 0 {
+		panic("a development policy source cannot be an empty string")
+	}
+
+	if strings.Contains(source, "/") {
+		if _, _, err := net.ParseCIDR(source); err != nil {
+			panic(source + " is an invalid CIDR block, this cannot be assigned as a development source")
+		}
+	}
+
+	policy.sources = append(policy.sources, source)
+
+	return policy
+}
+
+func (policy DevelopmentPolicy) Sources() []string {
+	sources := make([]string, len(policy.sources))
+	copy(sources, policy.sources)
+	return sources
+}
+
+func (policy DevelopmentPolicy) IsAllowedSource(sender *Address) bool {
+	if sender == nil {
+		return false
+	}
+
+	host := sender.GetHost()
+	ip := net.ParseIP(host)
+	if (ip == nil) && (host == "localhost") {
+		ip = net.ParseIP(Localhost)
+	}
+
+	for _, source := range policy.sources {
+		if source == host {
+			return true
+		}
+
+		if (ip == nil) || !strings.Contains(source, "/") {
+			continue
+		}
+
+		if _, block, err := net.ParseCIDR(source); (err == nil) && block.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,10 +3,13 @@ package fack
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -22,8 +25,21 @@ type Endpoint struct {
 	LastNonce         int64
 	GlobalPermissions *Permission            `json:"globalPermissions"`
 	LocalPermissions  map[string]*Permission `json:"localPermissions"`
+	Roles             []string               `json:"roles,omitempty"`
+	Priority          Priority               `json:"priority,omitempty"`
 }
 
+// Priority
+// The class an endpoint is admitted in when a Node queues requests, a queued request of a higher
+// class is admitted before (and may displace) those of a lower class.
+type Priority int8
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
 func NewEndpoint(name string, publicKey *ecdsa.PublicKey) *Endpoint {
 	endpoint := new(Endpoint)
 
@@ -59,6 +75,23 @@ func (endpoint *Endpoint) AddLocalPermission(route string, permission *Permissio
 	return false
 }
 
+func (endpoint *Endpoint) AddRole(role string) bool {
+	if endpoint.HasRole(role) {
+		return false
+	}
+	endpoint.Roles = append(endpoint.Roles, role)
+	return true
+}
+
+func (endpoint Endpoint) HasRole(role string) bool {
+	for _, r := range endpoint.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
 func (endpoint *Endpoint) GetPublicKey() (*ecdsa.PublicKey, bool) {
 	if (len(endpoint.X509) == 0) && (endpoint.PublicKey == nil) {
 		return nil, false
@@ -101,30 +134,68 @@ func (endpoint *Endpoint) PublicKeyToBytes() []byte {
 	return b
 }
 
+// Fingerprint
+// The SHA-256 digest of the x509 encoded public key as colon separated hex, used to compare
+// keys without exchanging them.
+func (endpoint *Endpoint) Fingerprint() (string, bool) {
+	publicKey, ok := endpoint.GetPublicKey()
+	if !ok {
+		return EmptyString, false
+	}
+
+	data, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return EmptyString, false
+	}
+
+	digest := sha256.Sum256(data)
+	pairs := make([]string, len(digest))
+	for i, b := range digest {
+		pairs[i] = hex.EncodeToString([]byte{b})
+	}
+
+	return "SHA256:" + strings.Join(pairs, ":"), true
+}
+
 func (endpoint *Endpoint) ValidateSource(request Request) bool {
+	return endpoint.VerifySource(request) == ReasonAuthorized
+}
+
+// VerifySource
+// Identical to ValidateSource, but returns the reason the signature was rejected.
+func (endpoint *Endpoint) VerifySource(request Request) DecisionReason {
 	// if we do not have a public key we cannot verify the ECDSA signature
 	if endpoint.PublicKey == nil {
-		return false
+		return ReasonMissingPublicKey
 	}
 	// we cannot accept the last received or previous nonce, or we risk a threat actor
 	// resending an intercepted nonce/signature to forge credentials
 	if request.GetNonce() <= endpoint.LastNonce {
-		return false
+		return ReasonStaleNonce
 	}
 
 	hash := request.GetHash()
 	signature := request.GetSignature()
-	isValidSignature := ecdsa.VerifyASN1(endpoint.PublicKey, hash, signature)
+	if ecdsa.VerifyASN1(endpoint.PublicKey, hash, signature) {
+		return ReasonAuthorized
+	}
+
+	if legacy, ok := request.(LegacyRequest); ok {
+		if hash, ok := legacy.GetLegacyHash(); ok && ecdsa.VerifyASN1(endpoint.PublicKey, hash, signature) {
+			return ReasonAuthorized
+		}
+	}
 
-	return isValidSignature
+	return ReasonSignatureMismatch
 }
 
 func (endpoint Endpoint) HasPermissionToUseMethod(route string, method HTTPMethod) bool {
 	if localPermission, ok := endpoint.LocalPermissions[route]; ok {
 		return localPermission.IsEnabled(method)
-	} else {
+	} else if endpoint.GlobalPermissions != nil {
 		return endpoint.GlobalPermissions.IsEnabled(method)
 	}
+	return false
 }
 
 func (endpoint Endpoint) String() string {
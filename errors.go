@@ -5,3 +5,38 @@ type NodeIllegalActionError struct{}
 func (e *NodeIllegalActionError) Error() string {
 	return "Illegal Action Given the Node's Current State"
 }
+
+type DevelopmentPolicyError struct{}
+
+func (e *DevelopmentPolicyError) Error() string {
+	return "Development Policy Cannot Be Enabled in a Production Environment"
+}
+
+type RouteConflictError struct {
+	Path     string
+	Existing string
+}
+
+func (e *RouteConflictError) Error() string {
+	if e.Path == e.Existing {
+		return "Path " + e.Path + " Is Already Registered"
+	}
+	return "Path " + e.Path + " Conflicts With " + e.Existing
+}
+
+type RouteNotFoundError struct {
+	Path string
+}
+
+func (e *RouteNotFoundError) Error() string {
+	return "Path " + e.Path + " Is Not Registered"
+}
+
+type MalformedRouteError struct {
+	Path   string
+	Reason string
+}
+
+func (e *MalformedRouteError) Error() string {
+	return "Path " + e.Path + " Is Malformed, " + e.Reason
+}
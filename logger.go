@@ -0,0 +1,121 @@
+package fack
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LogLevel int8
+
+const (
+	LevelDebug LogLevel = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (level LogLevel) ToString() string {
+	switch level {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Logger
+// A leveled, structured logger. The fields of an entry are given as alternating keys and
+// values (ex. "path", "/users", "status", 200) in the same form as log/slog.
+type Logger interface {
+	Enabled(level LogLevel) bool
+	Log(level LogLevel, message string, fields ...any)
+}
+
+// TextLogger
+// Writes one line per entry, the fields are written as key=value pairs after the message.
+type TextLogger struct {
+	writer io.Writer
+	level  LogLevel
+	mutex  sync.Mutex
+}
+
+// NewTextLogger
+// Entries below the level are discarded, the default logger of a Node writes entries of
+// LevelInfo and above to stderr.
+func NewTextLogger(writer io.Writer, level LogLevel) *TextLogger {
+	logger := new(TextLogger)
+	logger.writer = writer
+	logger.level = level
+
+	return logger
+}
+
+func DefaultLogger() Logger {
+	return NewTextLogger(os.Stderr, LevelInfo)
+}
+
+func (logger *TextLogger) Enabled(level LogLevel) bool {
+	return level >= logger.level
+}
+
+func (logger *TextLogger) Log(level LogLevel, message string, fields ...any) {
+	if !logger.Enabled(level) {
+		return
+	}
+
+	line := new(bytes.Buffer)
+	line.WriteString(time.Now().Format(time.RFC3339))
+	line.WriteByte(' ')
+	line.WriteString(level.ToString())
+	line.WriteByte(' ')
+	line.WriteString(message)
+
+	for i := 0; i < len(fields); i += 2 {
+		key := fmt.Sprint(fields[i])
+		var value any = "!MISSING"
+		if i+1 < len(fields) {
+			value = fields[i+1]
+		}
+		line.WriteByte(' ')
+		line.WriteString(key)
+		line.WriteByte('=')
+		line.WriteString(quoteValue(fmt.Sprint(value)))
+	}
+	line.WriteByte('\n')
+
+	// entries written from concurrent requests must not interleave
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	logger.writer.Write(line.Bytes())
+}
+
+func quoteValue(value string) string {
+	if (len(value) == 0) || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+type discardLogger struct{}
+
+// DiscardLogger
+// A Logger that drops every entry.
+func DiscardLogger() Logger {
+	return discardLogger{}
+}
+
+func (discardLogger) Enabled(level LogLevel) bool {
+	return false
+}
+
+func (discardLogger) Log(level LogLevel, message string, fields ...any) {}
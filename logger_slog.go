@@ -0,0 +1,40 @@
+//go:build go1.21
+
+package fack
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger
+// Adapts a log/slog Logger, LevelDebug through LevelError map onto the slog levels of the
+// same name.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{logger: logger}
+}
+
+func (logger *SlogLogger) Enabled(level LogLevel) bool {
+	return logger.logger.Enabled(context.Background(), slogLevel(level))
+}
+
+func (logger *SlogLogger) Log(level LogLevel, message string, fields ...any) {
+	logger.logger.Log(context.Background(), slogLevel(level), message, fields...)
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
@@ -0,0 +1,176 @@
+package fack
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Signature
+// A signature produced by a named Endpoint over the request hash generated with its own nonce.
+type Signature struct {
+	Endpoint  string `json:"endpoint"`
+	Nonce     int64  `json:"nonce"`
+	Signature []byte `json:"signature"`
+}
+
+// MultiSignature
+// A k-of-n requirement: the request must carry valid signatures from at least Threshold of
+// the named Signers before the Function is called.
+type MultiSignature struct {
+	Threshold int      `json:"threshold"`
+	Signers   []string `json:"signers"`
+}
+
+func NewMultiSignature(threshold int, signers ...string) *MultiSignature {
+	if (threshold < 1) || (threshold > len(signers)) {
+		panic("a multi-signature threshold must be within (inclusive) of 1 to the number of signers")
+	}
+
+	multiSignature := new(MultiSignature)
+	multiSignature.Threshold = threshold
+	multiSignature.Signers = signers
+
+	return multiSignature
+}
+
+func (multiSignature MultiSignature) IsSigner(name string) bool {
+	for _, signer := range multiSignature.Signers {
+		if signer == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CoSignatureHash
+// The hash a co-signer signs, the request hash generated with its own nonce bound to the path of
+// the route and the HTTP method, so a co-signature cannot be replayed on another route or method.
+func CoSignatureHash(request Request, nonce int64, path string, method HTTPMethod) []byte {
+	concatenated := append([]byte(method.ToString()+" "+path+":"), request.GetHashWithNonce(nonce)...)
+	hash := sha256.Sum256(concatenated)
+
+	return hash[:]
+}
+
+// CoSign
+// Adds the signature of the named endpoint to the request for the method on the path of the route.
+// Every co-signer holds its own nonce, re-signing a request replaces the previous signature of the
+// endpoint with a newer nonce.
+func CoSign(request Request, name string, key *ecdsa.PrivateKey, path string, method HTTPMethod) error {
+	nonce := GenerateNonce()
+	for _, signature := range request.GetSignatures() {
+		if (signature.Endpoint == name) && (signature.Nonce >= nonce) {
+			nonce = signature.Nonce + 1
+		}
+	}
+
+	hash := CoSignatureHash(request, nonce, path, method)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash)
+	if err != nil {
+		return errors.New("there was an error signing the request data")
+	}
+	request.AddSignature(Signature{Endpoint: name, Nonce: nonce, Signature: signature})
+
+	return nil
+}
+
+// AuthorizeMultiSig
+// Verifies every co-signature on the request against the endpoint registered under the signer's
+// name. A signature only counts towards the threshold if the signer is part of the requirement,
+// has permission to use the method on the path, and signed with a nonce newer than its last. The
+// nonce of every signature that verifies is recorded, so it cannot be replayed.
+func (na *Auth) AuthorizeMultiSig(request Request, path string, method HTTPMethod, requirement *MultiSignature) Decision {
+	decision := Decision{Path: path, Method: method.ToString()}
+
+	valid := make(map[string]bool)
+	rejected := make([]string, 0)
+
+	for _, signature := range request.GetSignatures() {
+		if !requirement.IsSigner(signature.Endpoint) || valid[signature.Endpoint] {
+			continue
+		}
+
+		// the nonce is checked and advanced under the same lock, so two requests carrying the same
+		// signature cannot both be verified
+		na.Mutex.Lock()
+		_, endpoint, ok := na.lookup(signature.Endpoint)
+
+		reason := ReasonAuthorized
+		if !ok {
+			reason = ReasonUnknownSource
+		} else if !endpoint.HasPermissionToUseMethod(path, method) {
+			reason = ReasonMethodNotPermitted
+		} else if endpoint.PublicKey == nil {
+			reason = ReasonMissingPublicKey
+		} else if signature.Nonce <= endpoint.LastNonce {
+			reason = ReasonStaleNonce
+		} else if !ecdsa.VerifyASN1(endpoint.PublicKey, CoSignatureHash(request, signature.Nonce, path, method), signature.Signature) {
+			reason = ReasonSignatureMismatch
+		} else {
+			endpoint.LastNonce = signature.Nonce
+		}
+		na.Mutex.Unlock()
+
+		if reason == ReasonAuthorized {
+			valid[signature.Endpoint] = true
+		} else {
+			rejected = append(rejected, signature.Endpoint+"="+reason.ToString())
+		}
+	}
+
+	signers := make([]string, 0, len(valid))
+	for _, signer := range requirement.Signers {
+		if valid[signer] {
+			signers = append(signers, signer)
+		}
+	}
+	decision.Endpoint = strings.Join(signers, ",")
+
+	decision.Rule = "multi-signature " + strconv.Itoa(len(valid)) + " of " + strconv.Itoa(requirement.Threshold) +
+		" required from [" + strings.Join(requirement.Signers, ",") + "]"
+	if len(rejected) > 0 {
+		decision.Rule += " rejected [" + strings.Join(rejected, ",") + "]"
+	}
+
+	if len(valid) >= requirement.Threshold {
+		decision.Authorized = true
+		decision.Reason = ReasonAuthorized
+	} else {
+		decision.Reason = ReasonInsufficientSignatures
+	}
+
+	return decision
+}
+
+// ExplainMultiSig
+// A dry-run of AuthorizeMultiSig, the co-signatures are not evaluated as there is no request to
+// check them against. The Decision is authorized if enough of the signers are registered with a
+// public key and permission to use the method on the path to meet the threshold.
+func (na *Auth) ExplainMultiSig(path string, method HTTPMethod, requirement *MultiSignature) Decision {
+	decision := Decision{Path: path, Method: method.ToString(), DryRun: true}
+
+	permitted := make([]string, 0)
+	na.Mutex.Lock()
+	for _, signer := range requirement.Signers {
+		if _, endpoint, ok := na.lookup(signer); ok && (endpoint.PublicKey != nil) && endpoint.HasPermissionToUseMethod(path, method) {
+			permitted = append(permitted, signer)
+		}
+	}
+	na.Mutex.Unlock()
+
+	decision.Rule = "multi-signature " + strconv.Itoa(requirement.Threshold) + " required from [" +
+		strings.Join(requirement.Signers, ",") + "] permitted [" + strings.Join(permitted, ",") + "]"
+
+	if len(permitted) >= requirement.Threshold {
+		decision.Authorized = true
+		decision.Reason = ReasonAuthorized
+	} else {
+		decision.Reason = ReasonInsufficientSignatures
+	}
+
+	return decision
+}
@@ -23,6 +23,19 @@ func HTTPMethodFromString(method string) HTTPMethod {
 	}
 }
 
+func (method HTTPMethod) ToString() string {
+	switch method {
+	case GET:
+		return "GET"
+	case POST:
+		return "POST"
+	case PULL:
+		return "PULL"
+	default:
+		return "DELETE"
+	}
+}
+
 func IsValidHTTPMethod(method string) bool {
 	return (method == "GET") || (method == "POST") || (method == "PULL") || (method == "DELETE")
 }
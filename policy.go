@@ -0,0 +1,441 @@
+package fack
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PolicyEffect uint8
+
+const (
+	PolicyAllow PolicyEffect = iota
+	PolicyDeny
+)
+
+func (effect PolicyEffect) ToString() string {
+	if effect == PolicyDeny {
+		return "deny"
+	}
+	return "allow"
+}
+
+// PolicyContext
+// The attributes of a request that a Policy rule can be written against.
+type PolicyContext struct {
+	Endpoint *Endpoint
+	Path     string
+	Method   HTTPMethod
+	Source   *Address
+	Time     time.Time
+	Params   []string
+}
+
+// Policy
+// An ordered list of rules evaluated after the Permission bitmap of an Endpoint has granted access
+// to a method. The first rule whose method, path and conditions match decides the outcome, when no
+// rule matches the Default effect is applied.
+//
+//	# comments start with a hash
+//	default allow
+//	allow POST /deploy when endpoint matches ci-*
+//	deny  POST /deploy
+//	deny  *    /export when role is reports and not time in 01:00-05:00
+//	deny  GET  /users/* when source in 10.0.0.0/8,192.168.0.0/16
+//	allow *    *        when param[0] is "dry run"
+type Policy struct {
+	Rules   []*PolicyRule
+	Default PolicyEffect
+}
+
+type PolicyRule struct {
+	Effect     PolicyEffect
+	Methods    Permission
+	Path       string
+	Line       int
+	Text       string
+	conditions []policyCondition
+}
+
+type policyCondition struct {
+	attribute string
+	index     int
+	operator  string
+	values    []string
+	negate    bool
+	blocks    []*net.IPNet
+	from      int // minutes after midnight
+	to        int
+}
+
+const (
+	policyAnyMethod = "*"
+	policyAnyPath   = "*"
+)
+
+func NewPolicy() *Policy {
+	policy := new(Policy)
+	policy.Default = PolicyAllow
+	return policy
+}
+
+func LoadPolicy(file string) (*Policy, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParsePolicy(f)
+}
+
+func ParsePolicy(reader io.Reader) (*Policy, error) {
+	policy := NewPolicy()
+
+	scanner := bufio.NewScanner(reader)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if (len(text) == 0) || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		tokens, err := tokenizePolicyLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("policy line %d: %s", line, err.Error())
+		}
+
+		if tokens[0] == "default" {
+			if len(tokens) != 2 {
+				return nil, fmt.Errorf("policy line %d: default expects a single effect", line)
+			}
+			effect, err := parsePolicyEffect(tokens[1])
+			if err != nil {
+				return nil, fmt.Errorf("policy line %d: %s", line, err.Error())
+			}
+			policy.Default = effect
+			continue
+		}
+
+		rule, err := parsePolicyRule(tokens)
+		if err != nil {
+			return nil, fmt.Errorf("policy line %d: %s", line, err.Error())
+		}
+		rule.Line = line
+		rule.Text = text
+
+		policy.Rules = append(policy.Rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Evaluate
+// Returns whether the context is allowed by the policy and the rule that decided it, the rule
+// is nil when no rule matched and the Default effect was applied.
+func (policy *Policy) Evaluate(context PolicyContext) (bool, *PolicyRule) {
+	for _, rule := range policy.Rules {
+		if rule.Matches(context) {
+			return rule.Effect == PolicyAllow, rule
+		}
+	}
+	return policy.Default == PolicyAllow, nil
+}
+
+func (rule *PolicyRule) Matches(context PolicyContext) bool {
+	if !rule.Methods.IsEnabled(context.Method) {
+		return false
+	}
+
+	if rule.Path != policyAnyPath {
+		if matched, _ := path.Match(rule.Path, context.Path); !matched {
+			return false
+		}
+	}
+
+	for _, condition := range rule.conditions {
+		if condition.matches(context) == condition.negate {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (rule PolicyRule) String() string {
+	return fmt.Sprintf("line %d: %s", rule.Line, rule.Text)
+}
+
+func (condition policyCondition) matches(context PolicyContext) bool {
+	switch condition.attribute {
+	case "endpoint":
+		if context.Endpoint == nil {
+			return false
+		}
+		return condition.compare(context.Endpoint.Name)
+	case "role":
+		if context.Endpoint == nil {
+			return false
+		}
+		for _, role := range context.Endpoint.Roles {
+			if condition.compare(role) {
+				return true
+			}
+		}
+		return false
+	case "path":
+		return condition.compare(context.Path)
+	case "method":
+		return condition.compare(context.Method.ToString())
+	case "source":
+		if context.Source == nil {
+			return false
+		}
+		if condition.operator != "in" {
+			return condition.compare(context.Source.GetHost())
+		}
+		ip := net.ParseIP(context.Source.GetHost())
+		for _, block := range condition.blocks {
+			if (ip != nil) && block.Contains(ip) {
+				return true
+			}
+		}
+		return condition.compare(context.Source.GetHost())
+	case "time":
+		minutes := context.Time.Hour()*60 + context.Time.Minute()
+		if condition.from <= condition.to {
+			return (minutes >= condition.from) && (minutes < condition.to)
+		}
+		// the range wraps around midnight (ex. 22:00-02:00)
+		return (minutes >= condition.from) || (minutes < condition.to)
+	case "param":
+		if condition.index >= len(context.Params) {
+			return false
+		}
+		return condition.compare(context.Params[condition.index])
+	}
+	return false
+}
+
+func (condition policyCondition) compare(value string) bool {
+	for _, expected := range condition.values {
+		switch condition.operator {
+		case "is", "in":
+			if value == expected {
+				return true
+			}
+		case "matches":
+			if matched, _ := path.Match(expected, value); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parsePolicyEffect(token string) (PolicyEffect, error) {
+	switch token {
+	case "allow":
+		return PolicyAllow, nil
+	case "deny":
+		return PolicyDeny, nil
+	}
+	return PolicyAllow, errors.New("unknown effect " + strconv.Quote(token) + ", expected allow or deny")
+}
+
+func parsePolicyRule(tokens []string) (*PolicyRule, error) {
+	if len(tokens) < 3 {
+		return nil, errors.New("a rule requires an effect, a method and a path")
+	}
+
+	rule := new(PolicyRule)
+
+	effect, err := parsePolicyEffect(tokens[0])
+	if err != nil {
+		return nil, err
+	}
+	rule.Effect = effect
+
+	if tokens[1] == policyAnyMethod {
+		rule.Methods.FullAccess()
+	} else {
+		for _, method := range strings.Split(tokens[1], ",") {
+			if !IsValidHTTPMethod(method) {
+				return nil, errors.New("unknown method " + strconv.Quote(method))
+			}
+			rule.Methods.Enable(HTTPMethodFromString(method))
+		}
+	}
+
+	rule.Path = tokens[2]
+	if _, err := path.Match(rule.Path, "/"); err != nil {
+		return nil, errors.New("malformed path pattern " + strconv.Quote(rule.Path))
+	}
+
+	if len(tokens) == 3 {
+		return rule, nil
+	}
+
+	if tokens[3] != "when" {
+		return nil, errors.New("expected when after the path, found " + strconv.Quote(tokens[3]))
+	}
+
+	remaining := tokens[4:]
+	for {
+		condition, rest, err := parsePolicyCondition(remaining)
+		if err != nil {
+			return nil, err
+		}
+		rule.conditions = append(rule.conditions, condition)
+
+		if len(rest) == 0 {
+			break
+		}
+		if rest[0] != "and" {
+			return nil, errors.New("expected and between conditions, found " + strconv.Quote(rest[0]))
+		}
+		remaining = rest[1:]
+	}
+
+	return rule, nil
+}
+
+func parsePolicyCondition(tokens []string) (policyCondition, []string, error) {
+	condition := policyCondition{}
+
+	if (len(tokens) > 0) && (tokens[0] == "not") {
+		condition.negate = true
+		tokens = tokens[1:]
+	}
+
+	if len(tokens) < 3 {
+		return condition, nil, errors.New("a condition requires an attribute, an operator and a value")
+	}
+
+	attribute := tokens[0]
+	if strings.HasPrefix(attribute, "param[") && strings.HasSuffix(attribute, "]") {
+		index, err := strconv.Atoi(attribute[len("param[") : len(attribute)-1])
+		if (err != nil) || (index < 0) {
+			return condition, nil, errors.New("malformed parameter index in " + strconv.Quote(attribute))
+		}
+		condition.index = index
+		attribute = "param"
+	}
+
+	switch attribute {
+	case "endpoint", "role", "path", "method", "source", "time", "param":
+		condition.attribute = attribute
+	default:
+		return condition, nil, errors.New("unknown attribute " + strconv.Quote(attribute))
+	}
+
+	condition.operator = tokens[1]
+	switch condition.operator {
+	case "is", "matches":
+		condition.values = []string{tokens[2]}
+	case "in":
+		condition.values = strings.Split(tokens[2], ",")
+	default:
+		return condition, nil, errors.New("unknown operator " + strconv.Quote(condition.operator))
+	}
+
+	if condition.attribute == "time" {
+		if condition.operator != "in" {
+			return condition, nil, errors.New("time only supports the in operator (ex. time in 01:00-05:00)")
+		}
+		from, to, err := parsePolicyTimeRange(tokens[2])
+		if err != nil {
+			return condition, nil, err
+		}
+		condition.from, condition.to = from, to
+	}
+
+	if (condition.attribute == "source") && (condition.operator == "in") {
+		for _, value := range condition.values {
+			if !strings.Contains(value, "/") {
+				continue
+			}
+			_, block, err := net.ParseCIDR(value)
+			if err != nil {
+				return condition, nil, errors.New("malformed CIDR block " + strconv.Quote(value))
+			}
+			condition.blocks = append(condition.blocks, block)
+		}
+	}
+
+	if condition.operator == "matches" {
+		if _, err := path.Match(tokens[2], ""); err != nil {
+			return condition, nil, errors.New("malformed pattern " + strconv.Quote(tokens[2]))
+		}
+	}
+
+	return condition, tokens[3:], nil
+}
+
+func parsePolicyTimeRange(value string) (int, int, error) {
+	bounds := strings.Split(value, "-")
+	if len(bounds) != 2 {
+		return 0, 0, errors.New("malformed time range " + strconv.Quote(value) + ", expected HH:MM-HH:MM")
+	}
+
+	minutes := [2]int{}
+	for i, bound := range bounds {
+		clock, err := time.Parse("15:04", bound)
+		if err != nil {
+			return 0, 0, errors.New("malformed time " + strconv.Quote(bound) + ", expected HH:MM")
+		}
+		minutes[i] = clock.Hour()*60 + clock.Minute()
+	}
+
+	return minutes[0], minutes[1], nil
+}
+
+// tokenizePolicyLine
+// Splits a rule on whitespace, values holding spaces can be wrapped in double quotes.
+func tokenizePolicyLine(text string) ([]string, error) {
+	tokens := make([]string, 0)
+
+	for len(text) > 0 {
+		text = strings.TrimLeft(text, " \t")
+		if len(text) == 0 {
+			break
+		}
+
+		if text[0] == '#' {
+			break
+		}
+
+		if text[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(text)
+			if err != nil {
+				return nil, errors.New("unterminated quoted value")
+			}
+			value, _ := strconv.Unquote(quoted)
+			tokens = append(tokens, value)
+			text = text[len(quoted):]
+			continue
+		}
+
+		end := strings.IndexAny(text, " \t")
+		if end == -1 {
+			end = len(text)
+		}
+		tokens = append(tokens, text[:end])
+		text = text[end:]
+	}
+
+	return tokens, nil
+}
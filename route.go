@@ -1,10 +1,24 @@
 package fack
 
+import (
+	"reflect"
+	"time"
+)
+
 type Route struct {
-	path         string
-	access       Permission
-	Debug        bool
-	RequiresAuth bool
+	path           string
+	description    string
+	timeout        time.Duration
+	concurrency    int
+	queue          int
+	bodyLimit      int64
+	access         Permission
+	multiSignature *MultiSignature
+	middleware     []Middleware
+	input          reflect.Type
+	output         reflect.Type
+	Debug          bool
+	RequiresAuth   bool
 }
 
 func NewRoute(path string) *Route {
@@ -26,17 +40,143 @@ func (route *Route) Path(path string) *Route {
 }
 
 func (route *Route) Auth(enable bool) *Route {
-	route.RequiresAuth = true
+	route.RequiresAuth = enable
 
 	return route
 }
 
+// Describe
+// A human-readable summary of the route, reported by the describe endpoint of the node.
+func (route *Route) Describe(description string) *Route {
+	route.description = description
+
+	return route
+}
+
+func (route Route) GetDescription() string {
+	return route.description
+}
+
+// Schema
+// The types the handler decodes its arguments into and encodes its result from, used to
+// document the route. Typed handlers declare them on registration, either may be nil.
+func (route *Route) Schema(input, output reflect.Type) *Route {
+	route.input = input
+	route.output = output
+
+	return route
+}
+
+func (route Route) InputType() reflect.Type {
+	return route.input
+}
+
+func (route Route) OutputType() reflect.Type {
+	return route.output
+}
+
 func (route *Route) Method(method HTTPMethod) *Route {
 	route.access.Enable(method)
 
 	return route
 }
 
+// MultiSig
+// Requires the request to carry valid signatures from at least k of the named endpoints,
+// independent of whether the route requires the sender to be authenticated.
+func (route *Route) MultiSig(k int, signers ...string) *Route {
+	route.multiSignature = NewMultiSignature(k, signers...)
+
+	return route
+}
+
+func (route Route) MultiSigRequirement() *MultiSignature {
+	return route.multiSignature
+}
+
+// Timeout
+// The deadline of the handler, the client receives a 504 once it is exceeded. A timeout of
+// zero (the default) lets the handler run until it finishes.
+func (route *Route) Timeout(timeout time.Duration) *Route {
+	route.timeout = timeout
+
+	return route
+}
+
+func (route Route) GetTimeout() time.Duration {
+	return route.timeout
+}
+
+// Concurrency
+// Bounds the number of handlers of the route running at once, up to queue further requests wait
+// for a handler to finish and any others are rejected with a 503. Requests are admitted once they
+// have passed the middleware chain. A limit of zero (the default) leaves the route unbounded.
+func (route *Route) Concurrency(limit, queue int) *Route {
+	if (limit < 0) || (queue < 0) {
+		panic("concurrency limit and queue cannot be negative")
+	}
+	route.concurrency = limit
+	route.queue = queue
+
+	return route
+}
+
+func (route Route) GetConcurrency() (limit, queue int) {
+	return route.concurrency, route.queue
+}
+
+// BodyLimit
+// The largest request body, in bytes, accepted by the route, larger bodies are rejected with a
+// 413. A limit of zero (the default) uses the body limit of the node.
+func (route *Route) BodyLimit(bytes int64) *Route {
+	if bytes < 0 {
+		panic("body limit cannot be negative")
+	}
+	route.bodyLimit = bytes
+
+	return route
+}
+
+func (route Route) GetBodyLimit() int64 {
+	return route.bodyLimit
+}
+
+// Use
+// Appends middleware to the route chain, it runs after the node-wide chain.
+func (route *Route) Use(middleware ...Middleware) *Route {
+	route.middleware = append(route.middleware, middleware...)
+
+	return route
+}
+
+func (route Route) GetMiddleware() []Middleware {
+	return route.middleware
+}
+
+// LogLevel
+// The level the entries logged for each request to the route are written at, a Debug route
+// logs them at LevelInfo so they are written by a logger at its default level.
+func (route Route) LogLevel() LogLevel {
+	if route.Debug {
+		return LevelInfo
+	}
+	return LevelDebug
+}
+
+func (route Route) GetPath() string {
+	return route.path
+}
+
+func (route Route) SupportedMethods() []HTTPMethod {
+	methods := make([]HTTPMethod, 0)
+	for _, method := range []HTTPMethod{GET, POST, PULL, DELETE} {
+		if route.access.IsEnabled(method) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
 func (route Route) IsMethodSupported(method HTTPMethod) bool {
 	return route.access.IsEnabled(method)
 }
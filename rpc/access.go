@@ -0,0 +1,121 @@
+package rpc
+
+import (
+	"encoding/json"
+	"github.com/GabeCordo/fack"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	AuthNotRequired    = "auth_not_required"
+	MethodNotSupported = "method_not_supported"
+)
+
+// MethodAccess
+// Whether an endpoint can use a single HTTP method on a route, and the reason why.
+type MethodAccess struct {
+	Method  string `json:"method"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+	Rule    string `json:"rule,omitempty"`
+}
+
+type RouteAccess struct {
+	Path         string         `json:"path"`
+	RequiresAuth bool           `json:"requiresAuth"`
+	MultiSig     string         `json:"multiSig,omitempty"`
+	Methods      []MethodAccess `json:"methods"`
+}
+
+// AccessReport
+// The effective access of an endpoint on every route registered with a node.
+type AccessReport struct {
+	Node     string        `json:"node"`
+	Endpoint string        `json:"endpoint"`
+	Routes   []RouteAccess `json:"routes"`
+}
+
+// EffectiveAccess
+// Computes what the endpoint registered under the name (or host) can do on every route of the
+// node. Routes that do not require auth grant every supported method, routes that do (or hold a
+// multi-signature requirement) are dry-run through Auth.Explain so the Permission bitmaps and any
+// Policy are applied. Signatures, nonces and development policies are not considered.
+func (node *Node) EffectiveAccess(endpoint string) AccessReport {
+	report := AccessReport{Node: node.name, Endpoint: endpoint, Routes: make([]RouteAccess, 0)}
+
+	for _, route := range node.Routes() {
+		access := RouteAccess{Path: route.GetPath(), RequiresAuth: route.RequiresAuth, Methods: make([]MethodAccess, 0)}
+
+		requirement := route.MultiSigRequirement()
+		if requirement != nil {
+			access.MultiSig = strings.Join(requirement.Signers, ",")
+		}
+
+		for _, method := range []fack.HTTPMethod{fack.GET, fack.POST, fack.PULL, fack.DELETE} {
+			methodAccess := MethodAccess{Method: method.ToString()}
+
+			if !route.IsMethodSupported(method) {
+				methodAccess.Reason = MethodNotSupported
+			} else if route.RequiresAuth || (requirement != nil) {
+				decision := node.auth.Explain(endpoint, route.GetPath(), method)
+				methodAccess.Allowed = decision.Authorized
+				methodAccess.Reason = decision.Reason.ToString()
+				methodAccess.Rule = decision.Rule
+			} else {
+				methodAccess.Allowed = true
+				methodAccess.Reason = AuthNotRequired
+			}
+
+			// the endpoint alone can never satisfy a multi-signature requirement, it is reported
+			// as allowed only if it is one of the signers that could contribute to the threshold
+			if methodAccess.Allowed && (requirement != nil) && !requirement.IsSigner(endpoint) {
+				methodAccess.Allowed = false
+				methodAccess.Reason = fack.ReasonInsufficientSignatures.ToString()
+			}
+
+			access.Methods = append(access.Methods, methodAccess)
+		}
+
+		report.Routes = append(report.Routes, access)
+	}
+
+	return report
+}
+
+func (report AccessReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// Table
+// A plain-text table of the report, where "-" marks a method the route does not support.
+func (report AccessReport) Table() string {
+	builder := new(strings.Builder)
+	writer := tabwriter.NewWriter(builder, 0, 4, 2, ' ', 0)
+
+	writer.Write([]byte("PATH\tAUTH\tGET\tPOST\tPULL\tDELETE\n"))
+	for _, route := range report.Routes {
+		row := []string{route.Path, "no"}
+		if route.RequiresAuth {
+			row[1] = "yes"
+		}
+		if len(route.MultiSig) > 0 {
+			row[1] += " (multi-sig)"
+		}
+
+		for _, method := range route.Methods {
+			if method.Reason == MethodNotSupported {
+				row = append(row, "-")
+			} else if method.Allowed {
+				row = append(row, "allow")
+			} else {
+				row = append(row, "deny")
+			}
+		}
+
+		writer.Write([]byte(strings.Join(row, "\t") + "\n"))
+	}
+	writer.Flush()
+
+	return builder.String()
+}
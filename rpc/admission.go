@@ -0,0 +1,250 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/GabeCordo/fack"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+const (
+	NodeScope  = "*"
+	Overloaded = "the node is at capacity and cannot accept the request"
+)
+
+var errOverloaded = errors.New(Overloaded)
+
+// waiter
+// A request queued for a slot, ready is closed once it is admitted or displaced.
+type waiter struct {
+	priority  fack.Priority
+	sequence  uint64
+	ready     chan struct{}
+	admitted  bool
+	displaced bool
+}
+
+// limiter
+// Bounds the handlers running in a scope (a route, or NodeScope for the whole node). Requests
+// beyond the limit wait in a queue ordered by priority and then arrival, requests beyond the
+// queue are rejected unless they outrank the lowest queued request, which is displaced instead.
+type limiter struct {
+	scope    string
+	limit    int
+	size     int
+	active   int
+	sequence uint64
+	waiting  []*waiter
+	metrics  *Metrics
+	mutex    sync.Mutex
+}
+
+func newLimiter(scope string, limit, size int, metrics *Metrics) *limiter {
+	limiter := new(limiter)
+	limiter.scope = scope
+	limiter.limit = limit
+	limiter.size = size
+	limiter.waiting = make([]*waiter, 0)
+	limiter.metrics = metrics
+
+	return limiter
+}
+
+// acquire
+// Blocks until the request is admitted, returns errOverloaded if it was rejected or displaced
+// and the error of the context if it was cancelled while queued.
+func (limiter *limiter) acquire(ctx context.Context, priority fack.Priority) error {
+	limiter.mutex.Lock()
+
+	if (limiter.active < limiter.limit) && (len(limiter.waiting) == 0) {
+		limiter.active++
+		limiter.mutex.Unlock()
+		return nil
+	}
+
+	if len(limiter.waiting) >= limiter.size {
+		lowest := len(limiter.waiting) - 1
+		if (lowest < 0) || (limiter.waiting[lowest].priority >= priority) {
+			limiter.mutex.Unlock()
+			limiter.metrics.reject(limiter.scope)
+			return errOverloaded
+		}
+
+		displaced := limiter.waiting[lowest]
+		limiter.waiting = limiter.waiting[:lowest]
+		displaced.displaced = true
+		close(displaced.ready)
+		limiter.metrics.queue(limiter.scope, -1)
+	}
+
+	limiter.sequence++
+	w := &waiter{priority: priority, sequence: limiter.sequence, ready: make(chan struct{})}
+	limiter.waiting = append(limiter.waiting, w)
+	sort.SliceStable(limiter.waiting, func(i, j int) bool {
+		return limiter.waiting[i].priority > limiter.waiting[j].priority
+	})
+	limiter.metrics.queue(limiter.scope, 1)
+	limiter.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		if w.displaced {
+			limiter.metrics.reject(limiter.scope)
+			return errOverloaded
+		}
+		return nil
+	case <-ctx.Done():
+		limiter.mutex.Lock()
+		defer limiter.mutex.Unlock()
+
+		// the slot may have been handed over while the context was being cancelled
+		if w.admitted {
+			limiter.releaseLocked()
+		} else if !w.displaced {
+			limiter.remove(w)
+			limiter.metrics.queue(limiter.scope, -1)
+		}
+		return ctx.Err()
+	}
+}
+
+// release
+// Hands the slot of a finished handler to the first queued request, or frees it.
+func (limiter *limiter) release() {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.releaseLocked()
+}
+
+func (limiter *limiter) releaseLocked() {
+	if len(limiter.waiting) == 0 {
+		limiter.active--
+		return
+	}
+
+	next := limiter.waiting[0]
+	limiter.waiting = limiter.waiting[1:]
+	next.admitted = true
+	close(next.ready)
+	limiter.metrics.queue(limiter.scope, -1)
+}
+
+func (limiter *limiter) remove(w *waiter) {
+	for i, queued := range limiter.waiting {
+		if queued == w {
+			limiter.waiting = append(limiter.waiting[:i], limiter.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// Concurrency
+// Bounds the number of handlers running at once across every route of the node, see
+// Route.Concurrency. The node-wide limit is applied once the route has admitted the request.
+func (node *Node) Concurrency(limit, queue int) {
+	if (limit < 0) || (queue < 0) {
+		panic("concurrency limit and queue cannot be negative")
+	}
+
+	if node.status == Startup {
+		node.limiter = nil
+		if limit > 0 {
+			node.limiter = newLimiter(NodeScope, limit, queue, node.metrics)
+		}
+	}
+}
+
+// routeLimiter
+// The limiter of the route, created on its first request once the Route has been configured.
+func (node *Node) routeLimiter(route *fack.Route) *limiter {
+	limit, queue := route.GetConcurrency()
+	if limit == 0 {
+		return nil
+	}
+
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	limiter, found := node.limiters[route]
+	if !found {
+		limiter = newLimiter(route.GetPath(), limit, queue, node.metrics)
+		node.limiters[route] = limiter
+	}
+	return limiter
+}
+
+// admit
+// Acquires a slot from the route and then the node limiters, the returned function releases them.
+// The route slot is granted first so that requests queued on a saturated route never hold a slot
+// of the node while they wait. The priority of the request is the Priority of the Endpoint whose
+// signature was verified, any other request is PriorityNormal.
+func (node *Node) admit(ctx context.Context, route *fack.Route, endpoint *fack.Endpoint) (func(), error) {
+	routeLimiter := node.routeLimiter(route)
+	if (node.limiter == nil) && (routeLimiter == nil) {
+		return func() {}, nil
+	}
+
+	priority := fack.PriorityNormal
+	if endpoint != nil {
+		priority = endpoint.Priority
+	}
+
+	acquired := make([]*limiter, 0, 2)
+	release := func() {
+		for i := len(acquired) - 1; i >= 0; i-- {
+			acquired[i].release()
+		}
+	}
+
+	for _, limiter := range []*limiter{routeLimiter, node.limiter} {
+		if limiter == nil {
+			continue
+		}
+		if err := limiter.acquire(ctx, priority); err != nil {
+			release()
+			return nil, err
+		}
+		acquired = append(acquired, limiter)
+	}
+
+	return release, nil
+}
+
+// admission
+// Admits the request into the limiters of its node and route before calling the handler, the slots
+// are released once the handler returns. A handler that outlives the timeout of its route still
+// holds its slots, invoke takes the release over so that it is only called once the handler has
+// returned.
+func admission(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		r, ok := request.(*Request)
+		if !ok {
+			next(request, response)
+			return
+		}
+
+		// the address of the sender is never used, a client can claim any address it likes
+		release, err := r.node.admit(r.HTTP().Context(), r.Route(), r.endpoint)
+		if err != nil {
+			if errors.Is(err, errOverloaded) {
+				r.node.setRetryAfter(r.header)
+				response.SetStatus(http.StatusServiceUnavailable).SetDescription(Overloaded)
+			} else {
+				response.SetStatus(http.StatusServiceUnavailable).SetDescription("Function Cancelled")
+			}
+			return
+		}
+
+		r.release = release
+		defer func() {
+			if r.release != nil {
+				r.release()
+			}
+		}()
+
+		next(request, response)
+	}
+}
@@ -0,0 +1,326 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+const (
+	MissingArgument  = "the argument does not exist"
+	MismatchArgument = "the argument cannot be converted to the requested type"
+	MixedArguments   = "a request cannot carry both legacy params and args"
+)
+
+// Arguments
+// The structured arguments of a Request, encoded on the wire as a JSON array when positional
+// or a JSON object when named. A Request holds one form or the other, never both.
+type Arguments struct {
+	Positional []any
+	Named      map[string]any
+}
+
+func (a *Arguments) isSet() bool {
+	return (a != nil) && !a.IsEmpty()
+}
+
+func (a Arguments) IsEmpty() bool {
+	return (len(a.Positional) == 0) && (len(a.Named) == 0)
+}
+
+func (a Arguments) MarshalJSON() ([]byte, error) {
+	if a.Named != nil {
+		return json.Marshal(a.Named)
+	}
+	if a.Positional == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(a.Positional)
+}
+
+func (a *Arguments) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := unmarshalNumbers(data, &value); err != nil {
+		return err
+	}
+
+	switch args := value.(type) {
+	case nil:
+		*a = Arguments{}
+	case []any:
+		*a = Arguments{Positional: args}
+	case map[string]any:
+		*a = Arguments{Named: args}
+	default:
+		return errors.New("args must be a JSON array or object")
+	}
+
+	return nil
+}
+
+// marshalWire
+// The binary codecs carry arguments in the same two forms as JSON.
+func (a Arguments) marshalWire() (any, error) {
+	if a.Named != nil {
+		return toWire(reflect.ValueOf(a.Named))
+	}
+	if a.Positional == nil {
+		return []any{}, nil
+	}
+	return toWire(reflect.ValueOf(a.Positional))
+}
+
+// unmarshalWire
+// Numbers are converted to json.Number, so handlers read the arguments (and the signature covers
+// them) the same way whichever codec they arrived through.
+func (a *Arguments) unmarshalWire(value any) error {
+	switch args := fromWireNumbers(value).(type) {
+	case nil:
+		*a = Arguments{}
+	case []any:
+		*a = Arguments{Positional: args}
+	case map[string]any:
+		*a = Arguments{Named: args}
+	default:
+		return errors.New("args must be an array or a map")
+	}
+
+	return nil
+}
+
+func fromWireNumbers(value any) any {
+	switch v := value.(type) {
+	case int64, uint64, float64:
+		if number, ok := toJSONNumber(v); ok {
+			return number
+		}
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case []any:
+		for i, item := range v {
+			v[i] = fromWireNumbers(item)
+		}
+	case map[string]any:
+		for key, item := range v {
+			v[key] = fromWireNumbers(item)
+		}
+	}
+	return value
+}
+
+// Canonical
+// A deterministic encoding of the arguments used when hashing a Request. Objects are written
+// with sorted keys, and numbers are written in their shortest form so that 1, 1.0 and 1e0 all
+// hash the same regardless of the codec the arguments travelled through.
+func (a Arguments) Canonical() ([]byte, error) {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	if err := unmarshalNumbers(data, &value); err != nil {
+		return nil, err
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := writeCanonical(buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeCanonical(buffer *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buffer.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			encoded, _ := json.Marshal(key)
+			buffer.Write(encoded)
+			buffer.WriteByte(':')
+			if err := writeCanonical(buffer, v[key]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case []any:
+		buffer.WriteByte('[')
+		for i, element := range v {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := writeCanonical(buffer, element); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	case json.Number:
+		number, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		buffer.WriteString(number)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buffer.Write(encoded)
+	}
+	return nil
+}
+
+func canonicalNumber(number json.Number) (string, error) {
+	if i, err := number.Int64(); err == nil {
+		return strconv.FormatInt(i, Decimal), nil
+	}
+
+	f, err := number.Float64()
+	if err != nil {
+		return "", err
+	}
+
+	// integral floats within the exactly representable range are written as integers
+	if (f == math.Trunc(f)) && (math.Abs(f) <= (1 << 53)) {
+		return strconv.FormatInt(int64(f), Decimal), nil
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64), nil
+}
+
+func unmarshalNumbers(data []byte, value any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(value)
+}
+
+// Argument
+// A single structured (or legacy string) argument of a Request, implements fack.Argument.
+type Argument struct {
+	value  any
+	exists bool
+}
+
+func (a Argument) Exists() bool {
+	return a.exists
+}
+
+func (a Argument) Value() any {
+	return a.value
+}
+
+func (a Argument) String() (string, error) {
+	switch v := normalize(a.value).(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", a.mismatch()
+}
+
+// Int
+// Legacy string arguments are parsed, so a client sending Param ["42"] and one sending
+// args [42] are read the same way by the handler.
+func (a Argument) Int() (int64, error) {
+	switch v := normalize(a.value).(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		if f, err := v.Float64(); (err == nil) && (f == math.Trunc(f)) {
+			return int64(f), nil
+		}
+	case string:
+		if i, err := strconv.ParseInt(v, Decimal, 64); err == nil {
+			return i, nil
+		}
+	}
+	return 0, a.mismatch()
+}
+
+func (a Argument) Float() (float64, error) {
+	switch v := normalize(a.value).(type) {
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f, nil
+		}
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, a.mismatch()
+}
+
+func (a Argument) Bool() (bool, error) {
+	switch v := normalize(a.value).(type) {
+	case bool:
+		return v, nil
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b, nil
+		}
+	}
+	return false, a.mismatch()
+}
+
+// Decode
+// Decodes the argument into v through its JSON encoding (ex. a nested object into a struct).
+func (a Argument) Decode(v any) error {
+	if !a.exists {
+		return errors.New(MissingArgument)
+	}
+
+	data, err := json.Marshal(a.value)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New(MismatchArgument)
+	}
+	return nil
+}
+
+func (a Argument) mismatch() error {
+	if !a.exists {
+		return errors.New(MissingArgument)
+	}
+	return errors.New(MismatchArgument)
+}
+
+// normalize
+// Arguments decoded from the wire are already JSON values, arguments added by a client in the
+// same process may be any Go value and are converted through their JSON encoding.
+func normalize(value any) any {
+	switch value.(type) {
+	case nil, string, bool, json.Number, []any, map[string]any:
+		return value
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized any
+	if err := unmarshalNumbers(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
@@ -0,0 +1,85 @@
+package rpc
+
+import (
+	"errors"
+	"github.com/GabeCordo/fack"
+	"io"
+)
+
+const (
+	DefaultBodyLimit int64 = 1 << 20
+	BodyTooLarge           = "the request body exceeds the limit of the function"
+)
+
+var errBodyTooLarge = errors.New(BodyTooLarge)
+
+// BodyLimit
+// The largest request body, in bytes, accepted by the Functions of the node unless their Route
+// sets its own, larger bodies are rejected with a 413. Defaults to DefaultBodyLimit, a limit of
+// zero leaves request bodies unbounded.
+func (node *Node) BodyLimit(bytes int64) {
+	if bytes < 0 {
+		panic("body limit cannot be negative")
+	}
+
+	if node.status == Startup {
+		node.bodyLimit = bytes
+	}
+}
+
+// DisallowUnknownFields
+// Rejects request bodies holding fields that are not part of the Request with a 400, instead of
+// silently ignoring them. Panics if a codec registered with the node is not a StrictCodec.
+func (node *Node) DisallowUnknownFields(enable bool) {
+	if enable {
+		for _, codec := range node.codecs {
+			if _, ok := codec.(StrictCodec); !ok {
+				panic("a node that disallows unknown fields requires a StrictCodec for " + codec.ContentType())
+			}
+		}
+	}
+
+	if node.status == Startup {
+		node.strict = enable
+	}
+}
+
+// LegacyParamSignatures
+// Accepts requests without args that are signed with the hash of clients that predate structured
+// arguments, which only covers the function and the nonce. The params of those requests are not
+// covered by their signature, the option should only be enabled until every client has upgraded.
+func (node *Node) LegacyParamSignatures(enable bool) {
+	if node.status == Startup {
+		node.legacyParams = enable
+	}
+}
+
+func (node *Node) getBodyLimit(route *fack.Route) int64 {
+	if limit := route.GetBodyLimit(); limit > 0 {
+		return limit
+	}
+	return node.bodyLimit
+}
+
+// readBody
+// Reads at most limit bytes of the body, a declared length over the limit is rejected before
+// anything is read. The limit of a compressed body applies to its decompressed length.
+func readBody(r io.Reader, length, limit int64) ([]byte, error) {
+	if limit == 0 {
+		return io.ReadAll(r)
+	}
+
+	if length > limit {
+		return nil, errBodyTooLarge
+	}
+
+	// one byte past the limit is enough to tell that a body without a Content-Length is too large
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, errBodyTooLarge
+	}
+	return body, nil
+}
@@ -0,0 +1,352 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"unicode/utf8"
+)
+
+const (
+	cborUnsigned byte = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	cborFalse      = 0xf4
+	cborTrue       = 0xf5
+	cborNull       = 0xf6
+	cborUndefined  = 0xf7
+	cborFloat64    = 0xfb
+	cborBreak      = 0xff
+	cborIndefinite = 31
+)
+
+// cborCodec
+// CBOR (RFC 8949) writing the shortest form of every length and integer and sorted map keys.
+// Indefinite lengths, half and single precision floats are read, tags are read but ignored.
+type cborCodec struct{}
+
+func (cborCodec) ContentType() string {
+	return CBORContentType
+}
+
+func (cborCodec) Marshal(v any) ([]byte, error) {
+	value, err := toWire(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := writeCBOR(buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (c cborCodec) Unmarshal(data []byte, v any) error {
+	return c.unmarshal(data, v, false)
+}
+
+func (c cborCodec) UnmarshalStrict(data []byte, v any) error {
+	return c.unmarshal(data, v, true)
+}
+
+func (cborCodec) unmarshal(data []byte, v any, strict bool) error {
+	reader := &wireReader{data: data}
+	value, err := readCBOR(reader, 0)
+	if err != nil {
+		return fmt.Errorf("cbor: %w", err)
+	}
+	if reader.remaining() > 0 {
+		return errors.New("cbor: invalid data after the top-level value")
+	}
+	return bindWire(value, v, strict)
+}
+
+func writeCBOR(buffer *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteByte(cborNull)
+	case bool:
+		if v {
+			buffer.WriteByte(cborTrue)
+		} else {
+			buffer.WriteByte(cborFalse)
+		}
+	case int64:
+		if v >= 0 {
+			writeCBORHead(buffer, cborUnsigned, uint64(v))
+		} else {
+			writeCBORHead(buffer, cborNegative, uint64(-1-v))
+		}
+	case uint64:
+		writeCBORHead(buffer, cborUnsigned, v)
+	case float64:
+		buffer.WriteByte(cborFloat64)
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(v))
+	case string:
+		writeCBORHead(buffer, cborText, uint64(len(v)))
+		buffer.WriteString(v)
+	case []byte:
+		writeCBORHead(buffer, cborBytes, uint64(len(v)))
+		buffer.Write(v)
+	case []any:
+		writeCBORHead(buffer, cborArray, uint64(len(v)))
+		for _, item := range v {
+			if err := writeCBOR(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		writeCBORHead(buffer, cborMap, uint64(len(v)))
+		for _, key := range sortedWireKeys(v) {
+			writeCBORHead(buffer, cborText, uint64(len(key)))
+			buffer.WriteString(key)
+			if err := writeCBOR(buffer, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cbor: values of type %T cannot be encoded", value)
+	}
+	return nil
+}
+
+// writeCBORHead
+// Writes the major type with its argument in the shortest of the five forms.
+func writeCBORHead(buffer *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buffer.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buffer.WriteByte(major | 24)
+		buffer.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buffer.WriteByte(major | 25)
+		binary.Write(buffer, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buffer.WriteByte(major | 26)
+		binary.Write(buffer, binary.BigEndian, uint32(n))
+	default:
+		buffer.WriteByte(major | 27)
+		binary.Write(buffer, binary.BigEndian, n)
+	}
+}
+
+func readCBOR(reader *wireReader, depth int) (any, error) {
+	if depth > maxWireDepth {
+		return nil, errWireDepth
+	}
+
+	b, err := reader.readByte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := b>>5, b&0x1f
+
+	if major == cborSimple {
+		return readCBORSimple(reader, b, info)
+	}
+
+	if info == cborIndefinite {
+		return readCBORIndefinite(reader, major, depth)
+	}
+
+	n, err := readCBORArgument(reader, info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		return wireInteger(n), nil
+	case cborNegative:
+		if n > math.MaxInt64 {
+			return nil, errors.New("negative integer overflows int64")
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		length, err := reader.length(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		data, _ := reader.next(length)
+		if major == cborBytes {
+			return append([]byte(nil), data...), nil
+		}
+		if !utf8.Valid(data) {
+			return nil, errors.New("text string is not valid UTF-8")
+		}
+		return string(data), nil
+	case cborArray:
+		length, err := reader.length(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]any, length)
+		for i := range items {
+			if items[i], err = readCBOR(reader, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborMap:
+		length, err := reader.length(n, 2)
+		if err != nil {
+			return nil, err
+		}
+		fields := make(map[string]any, length)
+		for i := 0; i < length; i++ {
+			if err := readCBORField(reader, fields, depth); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	}
+
+	// cborTag, the tagged item is read as it is
+	return readCBOR(reader, depth+1)
+}
+
+func readCBORArgument(reader *wireReader, info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info <= 27:
+		return reader.readUint(1 << (info - 24))
+	}
+	return 0, fmt.Errorf("reserved additional information %d", info)
+}
+
+func readCBORSimple(reader *wireReader, b, info byte) (any, error) {
+	switch b {
+	case cborFalse:
+		return false, nil
+	case cborTrue:
+		return true, nil
+	case cborNull, cborUndefined:
+		return nil, nil
+	}
+
+	switch info {
+	case 25:
+		bits, err := reader.readUint(2)
+		return halfFloat(uint16(bits)), err
+	case 26:
+		bits, err := reader.readUint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 27:
+		bits, err := reader.readUint(8)
+		return math.Float64frombits(bits), err
+	}
+	return nil, fmt.Errorf("unsupported simple value 0x%02x", b)
+}
+
+// readCBORIndefinite
+// Reads the chunks of an indefinite length string, or the items of an indefinite length array
+// or map, up to the break byte.
+func readCBORIndefinite(reader *wireReader, major byte, depth int) (any, error) {
+	if (major != cborBytes) && (major != cborText) && (major != cborArray) && (major != cborMap) {
+		return nil, fmt.Errorf("major type %d cannot have an indefinite length", major)
+	}
+
+	var chunks []byte
+	items := make([]any, 0)
+	fields := make(map[string]any)
+
+	for {
+		b, err := reader.peek()
+		if err != nil {
+			return nil, err
+		}
+		if b == cborBreak {
+			reader.offset++
+			break
+		}
+
+		switch major {
+		case cborBytes, cborText:
+			// every chunk is a definite length string of the same major type
+			if ((b >> 5) != major) || ((b & 0x1f) == cborIndefinite) {
+				return nil, errors.New("indefinite length string holds a chunk of another type")
+			}
+			chunk, err := readCBOR(reader, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if major == cborBytes {
+				chunks = append(chunks, chunk.([]byte)...)
+			} else {
+				chunks = append(chunks, chunk.(string)...)
+			}
+		case cborArray:
+			item, err := readCBOR(reader, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		case cborMap:
+			if err := readCBORField(reader, fields, depth); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch major {
+	case cborBytes:
+		return append([]byte{}, chunks...), nil
+	case cborText:
+		return string(chunks), nil
+	case cborArray:
+		return items, nil
+	}
+	return fields, nil
+}
+
+func readCBORField(reader *wireReader, fields map[string]any, depth int) error {
+	key, err := readCBOR(reader, depth+1)
+	if err != nil {
+		return err
+	}
+	name, ok := key.(string)
+	if !ok {
+		return errors.New("map keys must be text strings")
+	}
+
+	fields[name], err = readCBOR(reader, depth+1)
+	return err
+}
+
+func halfFloat(bits uint16) float64 {
+	exponent, fraction := int(bits>>10)&0x1f, float64(bits&0x3ff)
+
+	var f float64
+	switch exponent {
+	case 0:
+		f = math.Ldexp(fraction, -24)
+	case 0x1f:
+		if fraction == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(fraction+0x400, exponent-25)
+	}
+
+	if (bits >> 15) == 1 {
+		f = -f
+	}
+	return f
+}
@@ -0,0 +1,177 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSONContentType        = "application/json"
+	MessagePackContentType = "application/msgpack"
+	CBORContentType        = "application/cbor"
+	UnsupportedContent     = "the Content-Type of the request is not supported by the node"
+)
+
+// Codec
+// Encodes Requests and Responses on the wire. A Node decodes a request with the codec named by
+// its Content-Type and answers with the codec named by its Accept header, a client chooses the
+// codec of each Request it sends. Signatures never cover the encoded bytes, so a request signed
+// once verifies whichever codec it is sent with.
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// StrictCodec
+// A Codec that can reject fields unknown to the value it decodes into, used by nodes that
+// DisallowUnknownFields.
+type StrictCodec interface {
+	Codec
+	UnmarshalStrict(data []byte, v any) error
+}
+
+var (
+	JSON        StrictCodec = jsonCodec{}
+	MessagePack StrictCodec = msgpackCodec{}
+	CBOR        StrictCodec = cborCodec{}
+)
+
+// defaultCodecs
+// The codecs every node and client understands, by media type. MessagePack has never had a
+// registered media type, so the names in common use are all accepted.
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		JSONContentType:           JSON,
+		MessagePackContentType:    MessagePack,
+		"application/x-msgpack":   MessagePack,
+		"application/vnd.msgpack": MessagePack,
+		CBORContentType:           CBOR,
+	}
+}
+
+var clientCodecs = defaultCodecs()
+
+// Codec
+// Registers an additional codec, or replaces the codec of a media type, for the Functions of the node.
+// A node that DisallowUnknownFields only accepts a StrictCodec, any other would silently decode
+// unknown fields.
+func (node *Node) Codec(codec Codec) {
+	if _, ok := codec.(StrictCodec); node.strict && !ok {
+		panic("a node that disallows unknown fields requires a StrictCodec for " + codec.ContentType())
+	}
+
+	if node.status == Startup {
+		node.codecs[strings.ToLower(codec.ContentType())] = codec
+	}
+}
+
+// lookupCodec
+// The codec of the media type in the header, parameters are accepted as long as a charset (when
+// given) is UTF-8.
+func lookupCodec(codecs map[string]Codec, header string) (Codec, bool) {
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return nil, false
+	}
+
+	if charset, found := params["charset"]; found && !strings.EqualFold(charset, "utf-8") {
+		return nil, false
+	}
+
+	codec, found := codecs[mediaType]
+	return codec, found
+}
+
+func (node *Node) requestCodec(r *http.Request) (Codec, bool) {
+	return lookupCodec(node.codecs, r.Header.Get("Content-Type"))
+}
+
+// responseCodec
+// The codec the client accepts with the highest quality, or the codec of its request when it
+// accepts anything (or nothing the node can encode). Requests the node cannot decode are
+// answered in JSON.
+func (node *Node) responseCodec(r *http.Request) Codec {
+	codec, found := node.requestCodec(r)
+	if !found {
+		codec = JSON
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return codec
+	}
+
+	var preferred Codec
+	quality := 0.0
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, found := params["q"]; found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= quality {
+			continue
+		}
+
+		if (mediaType == "*/*") || (mediaType == "application/*") {
+			preferred, quality = codec, q
+		} else if candidate, found := node.codecs[mediaType]; found {
+			preferred, quality = candidate, q
+		}
+	}
+
+	if preferred == nil {
+		return codec
+	}
+	return preferred
+}
+
+// unmarshal
+// Decodes with the codec, strict decoding rejects unknown fields. Node.Codec and
+// DisallowUnknownFields ensure every codec of a strict node supports it.
+func unmarshal(codec Codec, data []byte, v any, strict bool) error {
+	if strictCodec, ok := codec.(StrictCodec); ok && strict {
+		return strictCodec.UnmarshalStrict(data, v)
+	}
+	return codec.Unmarshal(data, v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return JSONContentType
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) UnmarshalStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("invalid data after the top-level value of the request body")
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	GzipEncoding                = "gzip"
+	IdentityEncoding            = "identity"
+	DefaultCompressionThreshold = 1 << 10
+	UnsupportedEncoding         = "the Content-Encoding of the request is not supported by the node"
+)
+
+// Compressor
+// Compresses response bodies and decompresses request bodies of the Content-Encoding it names.
+// Gzip is built in, other encodings (ex. zstd) can be registered on a Node without it depending
+// on their implementation.
+type Compressor interface {
+	Encoding() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(r io.Reader) (io.ReadCloser, error)
+}
+
+var Gzip Compressor = new(gzipCompressor)
+
+type gzipCompressor struct {
+	writers sync.Pool
+}
+
+func (*gzipCompressor) Encoding() string {
+	return GzipEncoding
+}
+
+func (compressor *gzipCompressor) Compress(data []byte) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+
+	writer, ok := compressor.writers.Get().(*gzip.Writer)
+	if ok {
+		writer.Reset(buffer)
+	} else {
+		writer = gzip.NewWriter(buffer)
+	}
+	defer compressor.writers.Put(writer)
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (*gzipCompressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// Compressor
+// Registers a compressor for the Content-Encoding it names, or replaces the compressor of it.
+func (node *Node) Compressor(compressor Compressor) {
+	if node.status == Startup {
+		node.compressors[strings.ToLower(compressor.Encoding())] = compressor
+	}
+}
+
+// CompressionThreshold
+// Responses smaller than the threshold, in bytes, are sent uncompressed as compressing them
+// costs more than it saves. Defaults to DefaultCompressionThreshold.
+func (node *Node) CompressionThreshold(bytes int) {
+	if bytes < 0 {
+		panic("compression threshold cannot be negative")
+	}
+
+	if node.status == Startup {
+		node.threshold = bytes
+	}
+}
+
+// requestCompressor
+// The compressor of the request body, nil when it is not compressed. Returns false if the body
+// is compressed with an encoding the node has no compressor for, or with more than one.
+func (node *Node) requestCompressor(r *http.Request) (Compressor, bool) {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if (encoding == "") || (encoding == IdentityEncoding) {
+		return nil, true
+	}
+
+	compressor, found := node.compressors[encoding]
+	return compressor, found
+}
+
+// responseCompressor
+// The compressor the client accepts with the highest quality, nil if it accepts none of them.
+func (node *Node) responseCompressor(r *http.Request) Compressor {
+	accept := r.Header.Get("Accept-Encoding")
+	if accept == "" {
+		return nil
+	}
+
+	var preferred Compressor
+	quality := 0.0
+	for _, item := range strings.Split(accept, ",") {
+		// Accept-Encoding shares the parameter syntax of media types (ex. gzip;q=0.8)
+		encoding, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, found := params["q"]; found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= quality {
+			continue
+		}
+
+		if encoding == "*" {
+			if compressor, found := node.compressors[GzipEncoding]; found {
+				preferred, quality = compressor, q
+			}
+		} else if compressor, found := node.compressors[encoding]; found {
+			preferred, quality = compressor, q
+		}
+	}
+	return preferred
+}
+
+func defaultCompressors() map[string]Compressor {
+	return map[string]Compressor{GzipEncoding: Gzip}
+}
+
+func findCompressor(compressors []Compressor, encoding string) Compressor {
+	for _, compressor := range compressors {
+		if strings.EqualFold(compressor.Encoding(), strings.TrimSpace(encoding)) {
+			return compressor
+		}
+	}
+	return nil
+}
+
+// acceptEncoding
+// The Accept-Encoding header advertising the compressors in order of preference.
+func acceptEncoding(compressors []Compressor) string {
+	encodings := make([]string, len(compressors))
+	for i, compressor := range compressors {
+		encodings[i] = compressor.Encoding()
+	}
+	return strings.Join(encodings, ", ")
+}
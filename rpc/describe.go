@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"github.com/GabeCordo/fack"
+	"net/http"
+)
+
+// RouteDescription
+// What a client needs to know to call a route registered with the node.
+type RouteDescription struct {
+	Path         string   `json:"path"`
+	Methods      []string `json:"methods"`
+	RequiresAuth bool     `json:"requiresAuth"`
+	Threshold    int      `json:"threshold,omitempty"`
+	Signers      []string `json:"signers,omitempty"`
+	Timeout      string   `json:"timeout,omitempty"`
+	Description  string   `json:"description,omitempty"`
+}
+
+// ServiceDescription
+// Every route registered with a node, sorted by path.
+type ServiceDescription struct {
+	Node   string             `json:"node"`
+	Routes []RouteDescription `json:"routes"`
+}
+
+func DescribeRoute(route *fack.Route) RouteDescription {
+	description := RouteDescription{
+		Path:         route.GetPath(),
+		Methods:      make([]string, 0),
+		RequiresAuth: route.RequiresAuth,
+		Description:  route.GetDescription(),
+	}
+
+	for _, method := range route.SupportedMethods() {
+		description.Methods = append(description.Methods, method.ToString())
+	}
+
+	if requirement := route.MultiSigRequirement(); requirement != nil {
+		description.Threshold = requirement.Threshold
+		description.Signers = requirement.Signers
+	}
+
+	if route.GetTimeout() > 0 {
+		description.Timeout = route.GetTimeout().String()
+	}
+
+	return description
+}
+
+// Description
+// Describes every route registered with the node, including the describe endpoint itself.
+func (node *Node) Description() ServiceDescription {
+	description := ServiceDescription{Node: node.name, Routes: make([]RouteDescription, 0)}
+	for _, route := range node.Routes() {
+		description.Routes = append(description.Routes, DescribeRoute(route))
+	}
+	return description
+}
+
+// Describe
+// Registers a Function that returns the Description of the node under the "node" and "routes"
+// keys. Unlike Explain it does not require authentication by default, enable it on the returned
+// Route if the routes of the node should only be visible to trusted endpoints.
+func (node *Node) Describe(path string) *fack.Route {
+	return node.Function(path, func(request fack.Request, response fack.Response) {
+		description := node.Description()
+		response.SetStatus(http.StatusOK).SetDescription(Success).
+			Pair("node", description.Node).
+			Pair("routes", description.Routes)
+	}).Method(fack.GET).Describe("Lists the functions served by the node")
+}
@@ -0,0 +1,158 @@
+package rpc
+
+import (
+	"github.com/GabeCordo/fack"
+	"strings"
+)
+
+// Registrar
+// Anything Functions can be registered on, a Node or a Group of its routes.
+type Registrar interface {
+	Function(path string, handler fack.Router) *fack.Route
+	FunctionContext(path string, handler fack.ContextRouter) *fack.Route
+}
+
+// Group
+// Routes registered through a group share its path prefix, default methods, auth requirement,
+// debug flag and middleware. A nested group inherits the settings of its parent when a route is
+// registered, so the parent can still be configured after the nested group is created.
+type Group struct {
+	node       *Node
+	parent     *Group
+	prefix     string
+	methods    *fack.Permission
+	auth       *bool
+	debug      *bool
+	middleware []fack.Middleware
+}
+
+// Group
+// Creates a group of routes below the prefix, routes are only registered once a Function is
+// added to the group.
+func (node *Node) Group(prefix string) *Group {
+	group := new(Group)
+	group.node = node
+	group.prefix = prefix
+
+	return group
+}
+
+// Group
+// Creates a nested group below the prefix of this group.
+func (group *Group) Group(prefix string) *Group {
+	nested := group.node.Group(prefix)
+	nested.parent = group
+
+	return nested
+}
+
+// Method
+// Adds a default method of the group, a group that declares methods does not inherit those of
+// its parent.
+func (group *Group) Method(method fack.HTTPMethod) *Group {
+	if group.methods == nil {
+		group.methods = fack.NewPermission()
+	}
+	group.methods.Enable(method)
+
+	return group
+}
+
+func (group *Group) Auth(enable bool) *Group {
+	group.auth = &enable
+
+	return group
+}
+
+func (group *Group) Debug(enable bool) *Group {
+	group.debug = &enable
+
+	return group
+}
+
+// Use
+// Appends middleware to the group, it runs after the middleware of any parent group and before
+// the middleware added to the route itself.
+func (group *Group) Use(middleware ...fack.Middleware) *Group {
+	group.middleware = append(group.middleware, middleware...)
+
+	return group
+}
+
+func (group *Group) GetPrefix() string {
+	if group.parent == nil {
+		return group.prefix
+	}
+	return join(group.parent.GetPrefix(), group.prefix)
+}
+
+func (group *Group) Function(path string, handler fack.Router) *fack.Route {
+	return group.FunctionContext(path, fack.Adapt(handler))
+}
+
+// FunctionContext
+// Registers the handler on the node below the prefix of the group, the returned Route starts
+// with the settings of the group and can still be changed like any other.
+func (group *Group) FunctionContext(path string, handler fack.ContextRouter) *fack.Route {
+	route := group.node.FunctionContext(join(group.GetPrefix(), path), handler)
+
+	if methods := group.getMethods(); methods != nil {
+		for _, method := range []fack.HTTPMethod{fack.GET, fack.POST, fack.PULL, fack.DELETE} {
+			if methods.IsEnabled(method) {
+				route.Method(method)
+			}
+		}
+	}
+
+	if auth := group.getAuth(); auth != nil {
+		route.Auth(*auth)
+	}
+
+	if debug := group.getDebug(); debug != nil {
+		route.Debug = *debug
+	}
+
+	route.Use(group.getMiddleware()...)
+
+	return route
+}
+
+func (group *Group) getMethods() *fack.Permission {
+	if (group.methods != nil) || (group.parent == nil) {
+		return group.methods
+	}
+	return group.parent.getMethods()
+}
+
+func (group *Group) getAuth() *bool {
+	if (group.auth != nil) || (group.parent == nil) {
+		return group.auth
+	}
+	return group.parent.getAuth()
+}
+
+func (group *Group) getDebug() *bool {
+	if (group.debug != nil) || (group.parent == nil) {
+		return group.debug
+	}
+	return group.parent.getDebug()
+}
+
+func (group *Group) getMiddleware() []fack.Middleware {
+	if group.parent == nil {
+		return group.middleware
+	}
+
+	middleware := make([]fack.Middleware, 0)
+	middleware = append(middleware, group.parent.getMiddleware()...)
+	return append(middleware, group.middleware...)
+}
+
+// join
+// Joins a prefix and a path with a single slash, an empty path is registered on the prefix.
+func join(prefix, path string) string {
+	if len(path) == 0 {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,179 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/GabeCordo/fack"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	LivenessPath              = "/livez"
+	ReadinessPath             = "/readyz"
+	DefaultRetryAfter         = 5 * time.Second
+	DefaultHealthCheckTimeout = 2 * time.Second
+	HealthCheckPassed         = "ok"
+	NodeFrozen                = "the node is frozen and is not accepting requests"
+)
+
+// HealthCheck
+// Reports whether a dependency of the node (ex. a database) can be used, a failing check marks
+// the node as not ready. The context is cancelled once the check timeout is exceeded.
+type HealthCheck func(ctx context.Context) error
+
+// Health
+// The body of the liveness and readiness endpoints.
+type Health struct {
+	Node   string            `json:"node"`
+	Status string            `json:"status"`
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HealthCheck
+// Registers a named check consulted by the readiness endpoint, a check registered under an
+// existing name replaces it. Checks can be registered while the node is running.
+func (node *Node) HealthCheck(name string, check HealthCheck) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	node.checks[name] = check
+}
+
+// Freeze
+// Stops a running node from calling its functions, every call is answered with a 503 and the
+// Retry-After header until the node is resumed. The health endpoints keep answering.
+func (node *Node) Freeze() error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	if node.status != Running {
+		return &fack.NodeIllegalActionError{}
+	}
+	node.status = Frozen
+
+	return nil
+}
+
+func (node *Node) Resume() error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	if node.status != Frozen {
+		return &fack.NodeIllegalActionError{}
+	}
+	node.status = Running
+
+	return nil
+}
+
+// RetryAfter
+// The delay a frozen node asks clients to wait before retrying through the Retry-After header.
+func (node *Node) RetryAfter(delay time.Duration) {
+	if node.status == Startup {
+		node.retryAfter = delay
+	}
+}
+
+// Ready
+// Runs every health check, the node is ready once it is Running and every check passes.
+func (node *Node) Ready(ctx context.Context) Health {
+	health, _ := node.ready(ctx)
+	return health
+}
+
+// ready
+// Runs every health check, returning the status of the node the health was reported for.
+func (node *Node) ready(ctx context.Context) (Health, NodeStatus) {
+	node.mutex.Lock()
+	status := node.status
+	checks := make(map[string]HealthCheck, len(node.checks))
+	for name, check := range node.checks {
+		checks[name] = check
+	}
+	node.mutex.Unlock()
+
+	health := Health{Node: node.name, Status: status.fackStatus().ToString(), Ready: status == Running}
+	if len(checks) == 0 {
+		return health, status
+	}
+
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultHealthCheckTimeout)
+	defer cancel()
+
+	health.Checks = make(map[string]string)
+	for _, name := range names {
+		if err := checks[name](ctx); err != nil {
+			health.Checks[name] = err.Error()
+			health.Ready = false
+		} else {
+			health.Checks[name] = HealthCheckPassed
+		}
+	}
+
+	return health, status
+}
+
+// ServeHealth
+// Serves the liveness (LivenessPath) and readiness (ReadinessPath) endpoints. They are not
+// Functions: they answer any GET without a Request body or authentication, so orchestrators
+// can probe them. The liveness endpoint only fails once the node is Killed, the readiness
+// endpoint fails unless the node is Running and every HealthCheck passes.
+func (node *Node) ServeHealth() {
+	if node.status != Startup {
+		panic("Endpoints should not be added dynamically to the Node during runtime")
+	}
+
+	node.router.handle(LivenessPath, func(w http.ResponseWriter, r *http.Request) {
+		status := node.GetStatus()
+		health := Health{Node: node.name, Status: status.fackStatus().ToString(), Ready: status == Running}
+
+		code := http.StatusOK
+		if status == Killed {
+			code = http.StatusServiceUnavailable
+		}
+		node.writeHealth(w, r, code, health)
+	})
+
+	node.router.handle(ReadinessPath, func(w http.ResponseWriter, r *http.Request) {
+		health, status := node.ready(r.Context())
+
+		code := http.StatusOK
+		if !health.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		if status == Frozen {
+			node.setRetryAfter(w.Header())
+		}
+		node.writeHealth(w, r, code, health)
+	})
+}
+
+func (node *Node) writeHealth(w http.ResponseWriter, r *http.Request, code int, health Health) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(health)
+}
+
+func (node *Node) setRetryAfter(header http.Header) {
+	// Retry-After is expressed in whole seconds, a frozen node never asks for an immediate retry
+	seconds := int(node.retryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	header.Set("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,300 @@
+package rpc
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MetricsPath        = "/metrics"
+	MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+	OtherMethod        = "OTHER"
+)
+
+// DefaultLatencyBuckets
+// The upper bounds (in seconds) of the latency histogram buckets, matching the Prometheus defaults.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	route  string
+	method string
+	status int
+}
+
+type denialKey struct {
+	route  string
+	reason string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Metrics
+// Counters collected for every Function of a node, labelled by the route template so that the
+// number of series stays bounded by the number of routes.
+type Metrics struct {
+	buckets  []float64
+	requests map[requestKey]uint64
+	latency  map[string]*histogram
+	denials  map[denialKey]uint64
+	inFlight map[string]int64
+	queued   map[string]int64
+	rejected map[string]uint64
+	mutex    sync.Mutex
+}
+
+func NewMetrics(buckets ...float64) *Metrics {
+	metrics := new(Metrics)
+
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	metrics.buckets = append([]float64(nil), buckets...)
+	sort.Float64s(metrics.buckets)
+
+	metrics.requests = make(map[requestKey]uint64)
+	metrics.latency = make(map[string]*histogram)
+	metrics.denials = make(map[denialKey]uint64)
+	metrics.inFlight = make(map[string]int64)
+	metrics.queued = make(map[string]int64)
+	metrics.rejected = make(map[string]uint64)
+
+	return metrics
+}
+
+// begin
+// Marks a request to the route as in flight, the returned function records its outcome. Methods
+// outside of fack.HTTPMethod are counted as OtherMethod, the method is sent by the client and would
+// otherwise let it create any number of series.
+func (metrics *Metrics) begin(route, method string) func(status int) {
+	start := time.Now()
+	if !fack.IsValidHTTPMethod(method) {
+		method = OtherMethod
+	}
+
+	metrics.mutex.Lock()
+	metrics.inFlight[route]++
+	metrics.mutex.Unlock()
+
+	return func(status int) {
+		metrics.observe(route, method, status, time.Since(start))
+	}
+}
+
+func (metrics *Metrics) observe(route, method string, status int, duration time.Duration) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.inFlight[route]--
+	metrics.requests[requestKey{route: route, method: method, status: status}]++
+
+	h, found := metrics.latency[route]
+	if !found {
+		h = &histogram{counts: make([]uint64, len(metrics.buckets))}
+		metrics.latency[route] = h
+	}
+
+	seconds := duration.Seconds()
+	for i, bound := range metrics.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// deny
+// Counts a request to the route that was rejected by authentication, the reason is the
+// snake_case form of the fack.DecisionReason.
+func (metrics *Metrics) deny(route, reason string) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.denials[denialKey{route: route, reason: reason}]++
+}
+
+// queue
+// Tracks the requests waiting for a slot in the scope (a route, or NodeScope).
+func (metrics *Metrics) queue(scope string, delta int64) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.queued[scope] += delta
+}
+
+func (metrics *Metrics) reject(scope string) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	metrics.rejected[scope]++
+}
+
+// QueueDepth
+// The number of requests waiting for a slot in the scope, a route path or NodeScope.
+func (metrics *Metrics) QueueDepth(scope string) int64 {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	return metrics.queued[scope]
+}
+
+// Rejections
+// The number of requests rejected (or displaced from the queue) by the limit of the scope.
+func (metrics *Metrics) Rejections(scope string) uint64 {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	return metrics.rejected[scope]
+}
+
+// Requests
+// The number of calls to the route with the method that were answered with the status.
+func (metrics *Metrics) Requests(route, method string, status int) uint64 {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	return metrics.requests[requestKey{route: route, method: method, status: status}]
+}
+
+func (metrics *Metrics) Denials(route, reason string) uint64 {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	return metrics.denials[denialKey{route: route, reason: reason}]
+}
+
+func (metrics *Metrics) InFlight(route string) int64 {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	return metrics.inFlight[route]
+}
+
+// Write
+// Renders every metric in the Prometheus text exposition format, series are sorted so that the
+// output is stable between scrapes.
+func (metrics *Metrics) Write() []byte {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	buffer := new(bytes.Buffer)
+
+	writeHeader(buffer, "fack_requests_total", "counter", "Function calls by route, method and status.")
+	requests := make([]requestKey, 0, len(metrics.requests))
+	for key := range metrics.requests {
+		requests = append(requests, key)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].route != requests[j].route {
+			return requests[i].route < requests[j].route
+		}
+		if requests[i].method != requests[j].method {
+			return requests[i].method < requests[j].method
+		}
+		return requests[i].status < requests[j].status
+	})
+	for _, key := range requests {
+		fmt.Fprintf(buffer, "fack_requests_total{route=%s,method=%s,status=\"%d\"} %d\n",
+			label(key.route), label(key.method), key.status, metrics.requests[key])
+	}
+
+	writeHeader(buffer, "fack_request_duration_seconds", "histogram", "Function call latency by route.")
+	for _, route := range sortedKeys(metrics.latency) {
+		h := metrics.latency[route]
+		for i, bound := range metrics.buckets {
+			fmt.Fprintf(buffer, "fack_request_duration_seconds_bucket{route=%s,le=\"%s\"} %d\n",
+				label(route), strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(buffer, "fack_request_duration_seconds_bucket{route=%s,le=\"+Inf\"} %d\n", label(route), h.count)
+		fmt.Fprintf(buffer, "fack_request_duration_seconds_sum{route=%s} %s\n", label(route), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(buffer, "fack_request_duration_seconds_count{route=%s} %d\n", label(route), h.count)
+	}
+
+	writeHeader(buffer, "fack_auth_denials_total", "counter", "Requests rejected by authentication by route and reason.")
+	denials := make([]denialKey, 0, len(metrics.denials))
+	for key := range metrics.denials {
+		denials = append(denials, key)
+	}
+	sort.Slice(denials, func(i, j int) bool {
+		if denials[i].route != denials[j].route {
+			return denials[i].route < denials[j].route
+		}
+		return denials[i].reason < denials[j].reason
+	})
+	for _, key := range denials {
+		fmt.Fprintf(buffer, "fack_auth_denials_total{route=%s,reason=%s} %d\n", label(key.route), label(key.reason), metrics.denials[key])
+	}
+
+	writeHeader(buffer, "fack_requests_in_flight", "gauge", "Function calls currently being handled by route.")
+	for _, route := range sortedKeys(metrics.inFlight) {
+		fmt.Fprintf(buffer, "fack_requests_in_flight{route=%s} %d\n", label(route), metrics.inFlight[route])
+	}
+
+	writeHeader(buffer, "fack_queue_depth", "gauge", "Requests waiting for a concurrency slot by scope.")
+	for _, scope := range sortedKeys(metrics.queued) {
+		fmt.Fprintf(buffer, "fack_queue_depth{scope=%s} %d\n", label(scope), metrics.queued[scope])
+	}
+
+	writeHeader(buffer, "fack_admission_rejections_total", "counter", "Requests rejected by a concurrency limit by scope.")
+	for _, scope := range sortedKeys(metrics.rejected) {
+		fmt.Fprintf(buffer, "fack_admission_rejections_total{scope=%s} %d\n", label(scope), metrics.rejected[scope])
+	}
+
+	return buffer.Bytes()
+}
+
+func writeHeader(buffer *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(buffer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func label(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Metrics
+// The metrics collected for the Functions of the node, they are collected whether or not they
+// are served.
+func (node *Node) Metrics() *Metrics {
+	return node.metrics
+}
+
+// ServeMetrics
+// Serves the metrics of the node at MetricsPath in the Prometheus text exposition format. Like
+// the health endpoints it answers a plain HTTP GET without authentication.
+func (node *Node) ServeMetrics() {
+	if node.status != Startup {
+		panic("Endpoints should not be added dynamically to the Node during runtime")
+	}
+
+	node.router.handle(MetricsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", MetricsContentType)
+		w.Write(node.metrics.Write())
+	})
+}
@@ -0,0 +1,313 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"github.com/GabeCordo/fack"
+	"io"
+	"net/http"
+)
+
+// DefaultMiddleware
+// The checks every Function ran before middleware could be configured, in the order they ran.
+// Node.Middleware can be given a reordered or reduced copy of this chain to replace them, routes
+// that require authentication are authenticated whether or not the chain holds Authentication.
+func DefaultMiddleware() []fack.Middleware {
+	return []fack.Middleware{Recovery, MethodFilter, ContentType, DecodeBody, Authentication}
+}
+
+// Recovery
+// An unintended or unforeseen error improperly handled by a later middleware or the user-defined
+// handler function is returned to the client as a 500 rather than dropping the connection. The
+// panic (and its stack) is reported to the error hook of the node.
+func Recovery(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		defer func() {
+			if value := recover(); value != nil {
+				if r, ok := request.(*Request); ok {
+					r.node.report(request, newPanicError(value))
+				}
+				fail(response)
+			}
+		}()
+
+		next(request, response)
+	}
+}
+
+// MethodFilter
+// Rejects HTTP methods the route was not registered with.
+func MethodFilter(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		r, ok := request.(*Request)
+		if !ok {
+			next(request, response)
+			return
+		}
+
+		method := fack.HTTPMethodFromString(r.HTTP().Method)
+		if !r.Route().IsMethodSupported(method) {
+			r.node.logger.Log(r.Route().LogLevel(), "method not supported by the path",
+				"node", r.node.name, "path", r.Route().GetPath(), "method", r.HTTP().Method, "sender", r.HTTP().RemoteAddr)
+			response.SetStatus(http.StatusForbidden).SetDescription("HTTP Method Not Allowed")
+			return
+		}
+		next(request, response)
+	}
+}
+
+// ContentType
+// Rejects requests whose Content-Type header does not name a codec of the node with a 415.
+func ContentType(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		r, ok := request.(*Request)
+		if !ok {
+			next(request, response)
+			return
+		}
+
+		if _, found := r.node.requestCodec(r.HTTP()); !found {
+			r.node.logger.Log(r.Route().LogLevel(), "request content type is not supported",
+				"node", r.node.name, "path", r.Route().GetPath(), "sender", r.HTTP().RemoteAddr,
+				"content_type", r.HTTP().Header.Get("Content-Type"))
+			response.SetStatus(http.StatusUnsupportedMediaType).SetDescription(UnsupportedContent)
+			return
+		}
+
+		next(request, response)
+	}
+}
+
+// JSONContent
+// Rejects requests that do not declare a JSON body through the Content-Type header, for chains
+// that should only accept JSON rather than every codec of the node.
+func JSONContent(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		r, ok := request.(*Request)
+		if ok && !fack.IsUsingJSONContent(r.HTTP()) {
+			r.node.logger.Log(r.Route().LogLevel(), "request is not using JSON content",
+				"node", r.node.name, "path", r.Route().GetPath(), "sender", r.HTTP().RemoteAddr)
+			response.SetStatus(http.StatusBadRequest).SetDescription("Only JSON Content permitted")
+			return
+		}
+
+		next(request, response)
+	}
+}
+
+// DecodeBody
+// Unmarshals the body of the HTTP request into the Request with the codec named by its
+// Content-Type (JSON if the node has none), decompressing it first if it has a Content-Encoding.
+// Middleware placed before it in the chain only has access to the HTTP request.
+func DecodeBody(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		r, ok := request.(*Request)
+		if !ok {
+			next(request, response)
+			return
+		}
+
+		compressor, supported := r.node.requestCompressor(r.HTTP())
+		if !supported {
+			r.node.logger.Log(r.Route().LogLevel(), "request content encoding is not supported",
+				"node", r.node.name, "path", r.Route().GetPath(), "sender", r.HTTP().RemoteAddr,
+				"content_encoding", r.HTTP().Header.Get("Content-Encoding"))
+			response.SetStatus(http.StatusUnsupportedMediaType).SetDescription(UnsupportedEncoding)
+			return
+		}
+
+		body, length := io.Reader(r.HTTP().Body), r.HTTP().ContentLength
+		if compressor != nil {
+			decompressed, err := compressor.Decompress(body)
+			if err != nil {
+				response.SetStatus(http.StatusBadRequest).SetDescription(err.Error())
+				return
+			}
+			defer decompressed.Close()
+
+			// the Content-Length is that of the compressed body
+			body, length = decompressed, -1
+		}
+
+		httpBodyBytes, err := readBody(body, length, r.node.getBodyLimit(r.Route()))
+		if errors.Is(err, errBodyTooLarge) {
+			r.node.logger.Log(r.Route().LogLevel(), "request body exceeded the limit",
+				"node", r.node.name, "path", r.Route().GetPath(), "sender", r.HTTP().RemoteAddr)
+			response.SetStatus(http.StatusRequestEntityTooLarge).SetDescription(BodyTooLarge)
+			return
+		} else if err != nil {
+			response.SetStatus(http.StatusInternalServerError).SetDescription(err.Error())
+			return
+		}
+
+		codec, found := r.node.requestCodec(r.HTTP())
+		if !found {
+			codec = JSON
+		}
+
+		if err = unmarshal(codec, httpBodyBytes, r, r.node.strict); err != nil {
+			r.node.logger.Log(r.Route().LogLevel(), "request contained a malformed HTTP body",
+				"node", r.node.name, "path", r.Route().GetPath(), "sender", r.HTTP().RemoteAddr, "error", err.Error())
+			response.SetStatus(http.StatusBadRequest).SetDescription(err.Error())
+			return
+		}
+
+		// params are never read when args are present, a request carrying both could have its
+		// params evaluated by a policy that the handler never sees
+		if (len(r.Param) > 0) && r.Args.isSet() {
+			r.node.logger.Log(r.Route().LogLevel(), "request carried both params and args",
+				"node", r.node.name, "path", r.Route().GetPath(), "sender", r.HTTP().RemoteAddr)
+			response.SetStatus(http.StatusBadRequest).SetDescription(MixedArguments)
+			return
+		}
+
+		next(request, response)
+	}
+}
+
+// Authentication
+// Verifies the sender (and any co-signers) of requests sent to routes that require it.
+func Authentication(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		r, ok := request.(*Request)
+		if !ok {
+			next(request, response)
+			return
+		}
+
+		route := r.Route()
+		if !route.RequiresAuth && (route.MultiSigRequirement() == nil) {
+			// the endpoint does not require the destination ip of the request to have local or global
+			// permission to send messages to the Node
+			r.authenticated = true
+			next(request, response)
+			return
+		}
+
+		// we will see if the IP address has a mapped local or global permission to the endpoint
+		sender, err := fack.GetInternetProtocol(r.HTTP())
+		if err != nil {
+			response.SetStatus(http.StatusInternalServerError).SetDescription("Internet Protocol Parser Failed")
+			return
+		}
+
+		// Why not pass the lambda provided by the request to IsEndpointAuthorized?
+		//		-> the user is not forced to use the request.Send() method and can
+		//		   direct the request to an url they do not have permission for while
+		//		   inserting an url path as the lambda for a route they do have permission
+		//		   for
+		// Why not place method into request type as well?
+		//		-> a lambda can support > 1 HTTP method
+		//		-> it is safer to use a server-defined method that the node has control over
+		method := fack.HTTPMethodFromString(r.HTTP().Method)
+		decision, endpoint := r.node.authorize(r.HTTP(), route, sender, r, route.GetPath(), method)
+		if !decision.Authorized {
+			r.node.metrics.deny(route.GetPath(), decision.Reason.ToString())
+			// the request IP destination does not have local or global permission, the reason
+			// is only ever logged, the client is not told which stage rejected it
+			r.node.logger.Log(route.LogLevel(), "sender did not have permission",
+				"node", r.node.name, "path", route.GetPath(), "method", r.HTTP().Method,
+				"sender", sender.ToString(), "reason", decision.Reason.ToString(), "rule", decision.Rule)
+			response.SetStatus(http.StatusUnauthorized).SetDescription("Bye Bye.")
+			return
+		}
+
+		// the request IP destination either had local or global permission
+		r.authenticated = true
+		r.endpoint = endpoint
+		next(request, response)
+	}
+}
+
+// authenticated
+// Applies Authentication to requests that reach the handler without having passed through it, so
+// that a chain which leaves it out cannot serve routes that require authentication. Chains that
+// also leave out DecodeBody reject every request to those routes, there is no signature to verify.
+func authenticated(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		if r, ok := request.(*Request); ok && !r.authenticated {
+			Authentication(next)(request, response)
+			return
+		}
+		next(request, response)
+	}
+}
+
+// chain
+// Wraps the handler with the route middleware, and then the node middleware, so that the first
+// node middleware is the first to see the request.
+func chain(handler fack.Router, middleware ...[]fack.Middleware) fack.Router {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		for j := len(middleware[i]) - 1; j >= 0; j-- {
+			handler = middleware[i][j](handler)
+		}
+	}
+	return handler
+}
+
+// invoke
+// Calls the handler at the end of the middleware chain. When the Route declares a timeout the
+// handler runs on its own goroutine with its own Response, so that a handler which outlives its
+// deadline cannot write into the 504 sent to the client. The goroutine holds the admission slots
+// of the request until the handler returns, so a route stays bounded by its concurrency limit.
+func invoke(handler fack.ContextRouter) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		r, ok := request.(*Request)
+		if !ok || (r.Route().GetTimeout() <= 0) {
+			handler(request.Context(), request, response)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(request.Context(), r.Route().GetTimeout())
+		defer cancel()
+
+		release := r.release
+		r.release = nil
+
+		// the handler sees the deadline through Request.Context as well as its ctx argument
+		timed := *r
+		timed.ctx = ctx
+
+		isolated := NewResponse()
+		done := make(chan error, 1)
+		go func() {
+			defer func() {
+				// the stack is captured here, it is lost once the panic is re-raised
+				var err error
+				if value := recover(); value != nil {
+					err = newPanicError(value)
+				}
+				if release != nil {
+					release()
+				}
+				done <- err
+			}()
+			handler(ctx, &timed, isolated)
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				// re-raised on the goroutine of the middleware chain so Recovery can handle it
+				panic(err)
+			}
+			merge(response, isolated)
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				response.SetStatus(http.StatusGatewayTimeout).SetDescription("Function Deadline Exceeded")
+			} else {
+				response.SetStatus(http.StatusServiceUnavailable).SetDescription("Function Cancelled")
+			}
+		}
+	}
+}
+
+func merge(response fack.Response, from *Response) {
+	response.SetStatus(from.GetStatus())
+	if len(from.GetDescription()) > 0 {
+		response.SetDescription(from.GetDescription())
+	}
+	for key, value := range from.GetData() {
+		response.GetData()[key] = value
+	}
+}
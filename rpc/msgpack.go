@@ -0,0 +1,297 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// msgpackCodec
+// MessagePack (https://msgpack.org/) without extension types, integers are written in their
+// smallest form and map keys in sorted order.
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string {
+	return MessagePackContentType
+}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	value, err := toWire(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := writeMsgpack(buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (c msgpackCodec) Unmarshal(data []byte, v any) error {
+	return c.unmarshal(data, v, false)
+}
+
+func (c msgpackCodec) UnmarshalStrict(data []byte, v any) error {
+	return c.unmarshal(data, v, true)
+}
+
+func (msgpackCodec) unmarshal(data []byte, v any, strict bool) error {
+	reader := &wireReader{data: data}
+	value, err := readMsgpack(reader, 0)
+	if err != nil {
+		return fmt.Errorf("msgpack: %w", err)
+	}
+	if reader.remaining() > 0 {
+		return errors.New("msgpack: invalid data after the top-level value")
+	}
+	return bindWire(value, v, strict)
+}
+
+func writeMsgpack(buffer *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buffer.WriteByte(0xc0)
+	case bool:
+		if v {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case int64:
+		writeMsgpackInt(buffer, v)
+	case uint64:
+		writeMsgpackUint(buffer, v)
+	case float64:
+		buffer.WriteByte(0xcb)
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(v))
+	case string:
+		writeMsgpackHead(buffer, len(v), 0xa0, 32, 0xd9, true)
+		buffer.WriteString(v)
+	case []byte:
+		writeMsgpackHead(buffer, len(v), 0, 0, 0xc4, true)
+		buffer.Write(v)
+	case []any:
+		writeMsgpackHead(buffer, len(v), 0x90, 16, 0xdc, false)
+		for _, item := range v {
+			if err := writeMsgpack(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		writeMsgpackHead(buffer, len(v), 0x80, 16, 0xde, false)
+		for _, key := range sortedWireKeys(v) {
+			writeMsgpackHead(buffer, len(key), 0xa0, 32, 0xd9, true)
+			buffer.WriteString(key)
+			if err := writeMsgpack(buffer, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("msgpack: values of type %T cannot be encoded", value)
+	}
+	return nil
+}
+
+// writeMsgpackHead
+// Writes the type and length of a string, binary, array or map. Lengths below fixed are packed
+// into the fixed prefix, longer ones follow the first sized prefix: strings and binaries have an
+// 8, 16 and 32 bit form, arrays and maps only a 16 and 32 bit form.
+func writeMsgpackHead(buffer *bytes.Buffer, n int, prefix byte, fixed int, sized byte, has8Bit bool) {
+	switch {
+	case n < fixed:
+		buffer.WriteByte(prefix | byte(n))
+	case has8Bit && (n <= math.MaxUint8):
+		buffer.WriteByte(sized)
+		buffer.WriteByte(byte(n))
+	default:
+		if has8Bit {
+			sized++
+		}
+		if n <= math.MaxUint16 {
+			buffer.WriteByte(sized)
+			binary.Write(buffer, binary.BigEndian, uint16(n))
+		} else {
+			buffer.WriteByte(sized + 1)
+			binary.Write(buffer, binary.BigEndian, uint32(n))
+		}
+	}
+}
+
+func writeMsgpackInt(buffer *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0:
+		writeMsgpackUint(buffer, uint64(i))
+	case i >= -32:
+		buffer.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8:
+		buffer.WriteByte(0xd0)
+		buffer.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		buffer.WriteByte(0xd1)
+		binary.Write(buffer, binary.BigEndian, int16(i))
+	case i >= math.MinInt32:
+		buffer.WriteByte(0xd2)
+		binary.Write(buffer, binary.BigEndian, int32(i))
+	default:
+		buffer.WriteByte(0xd3)
+		binary.Write(buffer, binary.BigEndian, i)
+	}
+}
+
+func writeMsgpackUint(buffer *bytes.Buffer, u uint64) {
+	switch {
+	case u <= 0x7f:
+		buffer.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		buffer.WriteByte(0xcc)
+		buffer.WriteByte(byte(u))
+	case u <= math.MaxUint16:
+		buffer.WriteByte(0xcd)
+		binary.Write(buffer, binary.BigEndian, uint16(u))
+	case u <= math.MaxUint32:
+		buffer.WriteByte(0xce)
+		binary.Write(buffer, binary.BigEndian, uint32(u))
+	default:
+		buffer.WriteByte(0xcf)
+		binary.Write(buffer, binary.BigEndian, u)
+	}
+}
+
+func readMsgpack(reader *wireReader, depth int) (any, error) {
+	if depth > maxWireDepth {
+		return nil, errWireDepth
+	}
+
+	b, err := reader.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case (b & 0xe0) == 0xa0:
+		return readMsgpackString(reader, uint64(b&0x1f))
+	case (b & 0xf0) == 0x90:
+		return readMsgpackArray(reader, uint64(b&0x0f), depth)
+	case (b & 0xf0) == 0x80:
+		return readMsgpackMap(reader, uint64(b&0x0f), depth)
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := reader.readUint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		length, err := reader.length(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		data, _ := reader.next(length)
+		return append([]byte(nil), data...), nil
+	case 0xca:
+		bits, err := reader.readUint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := reader.readUint(8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := reader.readUint(1 << (b - 0xcc))
+		return wireInteger(u), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		u, err := reader.readUint(1 << (b - 0xd0))
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case 0xd0:
+			return int64(int8(u)), nil
+		case 0xd1:
+			return int64(int16(u)), nil
+		case 0xd2:
+			return int64(int32(u)), nil
+		}
+		return int64(u), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := reader.readUint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackString(reader, n)
+	case 0xdc, 0xdd:
+		n, err := reader.readUint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(reader, n, depth)
+	case 0xde, 0xdf:
+		n, err := reader.readUint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(reader, n, depth)
+	}
+
+	return nil, fmt.Errorf("unsupported type 0x%02x", b)
+}
+
+func readMsgpackString(reader *wireReader, n uint64) (any, error) {
+	length, err := reader.length(n, 1)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := reader.next(length)
+	return string(data), nil
+}
+
+func readMsgpackArray(reader *wireReader, n uint64, depth int) (any, error) {
+	length, err := reader.length(n, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]any, length)
+	for i := range items {
+		if items[i], err = readMsgpack(reader, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func readMsgpackMap(reader *wireReader, n uint64, depth int) (any, error) {
+	length, err := reader.length(n, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]any, length)
+	for i := 0; i < length; i++ {
+		key, err := readMsgpack(reader, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, errors.New("map keys must be strings")
+		}
+
+		if fields[name], err = readMsgpack(reader, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
@@ -3,15 +3,20 @@ package rpc
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/GabeCordo/fack"
-	"io"
-	"log"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+const (
+	DefaultDrainTimeout = 5 * time.Second
+)
+
 type NodeStatus int
 
 const (
@@ -21,19 +26,58 @@ const (
 	Killed
 )
 
-type Node struct {
-	name string `json:"name"`
-
-	address *fack.Address `json:"address"`
-	status  NodeStatus    `json:"status"`
-
-	auth *fack.Auth
+// fackStatus
+// The fack.NodeStatus of the status, the two enums are mapped explicitly as their values are not
+// guaranteed to match.
+func (status NodeStatus) fackStatus() fack.NodeStatus {
+	switch status {
+	case Startup:
+		return fack.Startup
+	case Running:
+		return fack.Running
+	case Frozen:
+		return fack.Frozen
+	default:
+		return fack.Killed
+	}
+}
 
-	mux    *http.ServeMux
-	server *http.Server
-	mutex  sync.Mutex
+type Node struct {
+	name string
+
+	address *fack.Address
+	status  NodeStatus
+
+	auth        *fack.Auth
+	logger      fack.Logger
+	environment fack.Environment
+	development *fack.DevelopmentPolicy
+
+	routes       map[string]*fack.Route
+	middleware   []fack.Middleware
+	checks       map[string]HealthCheck
+	metrics      *Metrics
+	errorHook    ErrorHook
+	limiter      *limiter
+	limiters     map[*fack.Route]*limiter
+	retryAfter   time.Duration
+	bodyLimit    int64
+	strict       bool
+	legacyParams bool
+	codecs       map[string]Codec
+	compressors  map[string]Compressor
+	threshold    int
+
+	router       *router
+	server       *http.Server
+	drainTimeout time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	mutex        sync.Mutex
 }
 
+var _ fack.Node = (*Node)(nil)
+
 // NewNode
 // address : address -> defines the listening host and port
 // auth : *auth ->
@@ -48,6 +92,12 @@ func NewNode(address *fack.Address, optional ...any) *Node {
 			node.name = val
 		case *fack.Auth:
 			node.auth = val // default: nil
+		case fack.Environment:
+			node.environment = val // default: fack.Development
+		case *fack.DevelopmentPolicy:
+			node.development = val // default: nil
+		case fack.Logger:
+			node.logger = val // default: fack.DefaultLogger()
 		}
 	}
 
@@ -56,13 +106,35 @@ func NewNode(address *fack.Address, optional ...any) *Node {
 		node.name = fack.GenerateRandomString(int(fack.GenerateNonce()))
 	}
 
+	if node.logger == nil {
+		node.logger = fack.DefaultLogger()
+	}
+
 	// if no auth node is passed in, generate an empty one
 	if node.auth == nil {
 		node.auth = fack.NewAuth()
 	}
 
-	node.mux = http.NewServeMux()
+	// a development policy passed alongside a production environment is discarded rather
+	// than trusted, there is no configuration where both can be active at the same time
+	if node.IsProduction() {
+		node.development = nil
+	}
+
+	node.routes = make(map[string]*fack.Route)
+	node.middleware = DefaultMiddleware()
+	node.checks = make(map[string]HealthCheck)
+	node.metrics = NewMetrics()
+	node.limiters = make(map[*fack.Route]*limiter)
+	node.retryAfter = DefaultRetryAfter
+	node.bodyLimit = DefaultBodyLimit
+	node.codecs = defaultCodecs()
+	node.compressors = defaultCompressors()
+	node.threshold = DefaultCompressionThreshold
+	node.router = newRouter()
 	node.server = new(http.Server)
+	node.drainTimeout = DefaultDrainTimeout
+	node.ctx, node.cancel = context.WithCancel(context.Background())
 
 	node.address = address
 	node.status = Startup
@@ -94,123 +166,488 @@ func (node *Node) Auth(auth *fack.Auth) {
 	}
 }
 
-func (node *Node) Function(path string, handler fack.Router) *fack.Route {
+// Logger
+// Replaces the logger of the node, entries logged for each request are written at the
+// LogLevel of their Route.
+func (node *Node) Logger(logger fack.Logger) {
+	if node.status == Startup {
+		node.logger = logger
+	}
+}
+
+// Environment
+// Production nodes can never hold a development policy, switching a node into the
+// Production environment will discard any policy previously assigned to it.
+func (node *Node) Environment(environment fack.Environment) {
+	if node.status == Startup {
+		node.environment = environment
+		if node.IsProduction() {
+			node.development = nil
+		}
+	}
+}
 
-	// functions should be assigned before the node is running
+// Development
+// Assigns the policy of sources that may skip authentication on routes that require it.
+// Returns a DevelopmentPolicyError if the binary was built with the production tag or the
+// node is configured for the Production environment.
+func (node *Node) Development(policy *fack.DevelopmentPolicy) error {
 	if node.status != Startup {
-		panic("Endpoints should not be added dynamically to the Node during runtime")
+		return &fack.NodeIllegalActionError{}
 	}
 
-	route := fack.EmptyRoute()
+	if (policy != nil) && node.IsProduction() {
+		return &fack.DevelopmentPolicyError{}
+	}
 
-	//node.mux.HandleFunc(path, TestHandler)
-	node.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		defer r.Body.Close()
+	node.development = policy
 
-		response := NewResponse()
-		defer response.Send(w)
+	return nil
+}
 
-		fmt.Printf(r.Method)
+func (node *Node) IsProduction() bool {
+	return fack.ProductionBuild || (node.environment == fack.Production)
+}
 
-		method := fack.HTTPMethodFromString(r.Method)
-		if !route.IsMethodSupported(method) {
-			log.Printf("[%s] Request to %s failed; Path does not support %s\n", node.name, path, r.Method)
-			response.AddStatus(http.StatusForbidden, "HTTP Method Not Allowed")
-			return
-		} else {
-			log.Printf("[%s] Request %s used an approved %s method.\n", node.name, r.Host, r.Method)
-		}
+// isDevelopmentSource
+// The peer address of the TCP connection is used rather than the X-FORWARDED-FOR header,
+// otherwise any caller could claim to be an allowed source by setting the header.
+func (node *Node) isDevelopmentSource(r *http.Request) bool {
+	if (node.development == nil) || node.IsProduction() {
+		return false
+	}
 
-		if !fack.IsUsingJSONContent(r) {
-			log.Println("not using JSON content")
-			response.AddStatus(http.StatusBadRequest, "Only JSON Content permitted")
-			return
+	peer, err := fack.NewAddress(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+
+	return node.development.IsAllowedSource(peer)
+}
+
+// authorize
+// The development policy is consulted before the auth database so that a bypass is
+// still reported as a decision rather than silently granted. A route holding a
+// multi-signature requirement is only authorized once the sender (if required) and
+// the co-signers have all been verified, and only for the function named in the request
+// body. The Endpoint returned is the sender whose signature was verified, nil if the
+// route did not require one.
+func (node *Node) authorize(r *http.Request, route *fack.Route, sender *fack.Address, request fack.Request, path string, method fack.HTTPMethod) (fack.Decision, *fack.Endpoint) {
+	if node.isDevelopmentSource(r) {
+		return fack.Decision{
+			Authorized: true,
+			Reason:     fack.ReasonDevelopmentBypass,
+			Path:       path,
+			Method:     method.ToString(),
+			Rule:       "development policy " + r.RemoteAddr,
+		}, nil
+	}
+
+	// the signature covers the function named in the body, not the path the request was routed
+	// on, a request signed for /users/42 must not be accepted by /users/43
+	if request.GetEndpoint() != r.URL.Path {
+		return fack.Decision{
+			Reason: fack.ReasonPathMismatch,
+			Path:   path,
+			Method: method.ToString(),
+			Rule:   "function " + request.GetEndpoint() + " sent to " + r.URL.Path,
+		}, nil
+	}
+
+	var endpoint *fack.Endpoint
+	decision := fack.Decision{Authorized: true, Reason: fack.ReasonAuthorized, Path: path, Method: method.ToString()}
+	if route.RequiresAuth {
+		decision, endpoint = node.auth.AuthorizeEndpoint(sender, request, path, method)
+	}
+
+	if requirement := route.MultiSigRequirement(); decision.Authorized && (requirement != nil) {
+		decision = node.auth.AuthorizeMultiSig(request, path, method, requirement)
+	}
+
+	if !decision.Authorized {
+		return decision, nil
+	}
+	return decision, endpoint
+}
+
+// explain
+// A dry-run of authorize for the endpoint registered under the name (or host) given. The path is
+// resolved to its Route as a request to it would be, so permissions are looked up against the
+// template and a route that does not require auth is reported as such. Signatures and nonces are
+// not evaluated, a multi-signature requirement is authorized if enough signers could meet it.
+func (node *Node) explain(endpoint, path string, method fack.HTTPMethod) fack.Decision {
+	decision := fack.Decision{Path: path, Method: method.ToString(), DryRun: true}
+
+	route := node.resolve(path)
+	if route == nil {
+		decision.Reason = fack.ReasonRouteNotFound
+		decision.Rule = "no function is registered for " + path
+		return decision
+	}
+	decision.Path = route.GetPath()
+
+	host, record, found := node.auth.Lookup(endpoint)
+	if !found {
+		host = endpoint
+	} else {
+		decision.Endpoint = record.Name
+	}
+
+	source := fack.EmptyAddress()
+	source.Host = host
+	if (node.development != nil) && !node.IsProduction() && node.development.IsAllowedSource(source) {
+		decision.Authorized = true
+		decision.Reason = fack.ReasonDevelopmentBypass
+		decision.Rule = "development policy " + host
+		return decision
+	}
+
+	requirement := route.MultiSigRequirement()
+	if !route.RequiresAuth && (requirement == nil) {
+		decision.Authorized = true
+		decision.Reason = fack.ReasonAuthorized
+		decision.Rule = "route does not require authentication"
+		return decision
+	}
+
+	if route.RequiresAuth {
+		decision = node.auth.Explain(endpoint, route.GetPath(), method)
+		if !decision.Authorized {
+			return decision
 		}
+	}
 
-		// we will see if the IP address has a mapped local or global permission to the endpoint
-		sender, error := fack.GetInternetProtocol(r)
-		if error != nil {
-			response.AddStatus(http.StatusInternalServerError, "Internet Protocol Parser Failed")
-			return
+	if requirement != nil {
+		decision = node.auth.ExplainMultiSig(route.GetPath(), method, requirement)
+		if found {
+			decision.Endpoint = record.Name
 		}
+	}
+
+	return decision
+}
 
-		/** Unmarshal the JSON body to Request Struct */
-		var body *Request = &Request{}
+// resolve
+// The Route a request to the path is handled by, nil if no registered path matches it.
+func (node *Node) resolve(path string) *fack.Route {
+	registered, handler, _ := node.router.match(path)
+	if handler == nil {
+		return nil
+	}
+
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	return node.routes[registered]
+}
 
-		httpBodyBytes, err := io.ReadAll(r.Body)
-		if err != nil {
-			response.AddStatus(http.StatusInternalServerError, err.Error())
+// Explain
+// Registers an admin Function that dry-runs authorization for an endpoint without requiring
+// a signed request from it. The request params are the endpoint name (or host), the path
+// and the HTTP method; the Decision is returned under the "decision" key. The path is resolved
+// to the Route that would handle it and every stage of authorization but the signatures is
+// applied. The Function requires authentication by default.
+func (node *Node) Explain(path string) *fack.Route {
+	return node.Function(path, func(request fack.Request, response fack.Response) {
+		params := request.GetParams()
+		if len(params) != 3 {
+			response.SetStatus(http.StatusBadRequest).SetDescription(SyntaxMismatch)
 			return
 		}
-		s := string(httpBodyBytes)
-		err = json.Unmarshal([]byte(s), body)
-		if err != nil {
-			log.Println(err.Error())
-			if route.Debug {
-				log.Printf("[%s] Request %s contained a malformed HTTP body\n", node.name, r.Host)
-			}
-			response.AddStatus(http.StatusBadRequest, err.Error())
+
+		if !fack.IsValidHTTPMethod(params[2]) {
+			response.SetStatus(http.StatusBadRequest).SetDescription(BadArgument)
 			return
 		}
 
-		if route.RequiresAuth {
-			// Why not pass the lambda provided by the request to IsEndpointAuthorized?
-			//		-> the user is not forced to use the request.Send() method and can
-			//		   direct the request to an url they do not have permission for while
-			//		   inserting an url path as the lambda for a route they do have permission
-			//		   for
-			// Why not place method into request type as well?
-			//		-> a lambda can support > 1 HTTP method
-			//		-> it is safer to use a server-defined method that the node has control over
-			if (route.Debug && sender.IsLocalHost()) || node.auth.IsEndpointAuthorized(sender, body, path, method) {
-				// the request IP destination either had local or global permission
-				handler(body, response)
-			} else {
-				// the request IP destination does not have local or global permission
-				if route.Debug {
-					log.Printf("Request %s attempted to submit a request to %s (%s); did not have permission\n", path, r.Method)
-				}
-				response.AddStatus(http.StatusUnauthorized, "Bye Bye.")
-			}
-		} else {
-			// the endpoint does not require the destination ip of the request to have local or global
-			// permission to send messages to the Node
-			handler(body, response)
-		}
+		decision := node.explain(params[0], params[1], fack.HTTPMethodFromString(params[2]))
+		response.SetStatus(http.StatusOK).SetDescription(Success).Pair("decision", decision)
+	}).Method(fack.GET).Method(fack.POST).Auth(true).Describe("Dry-runs authorization for an endpoint")
+}
+
+func (node *Node) Function(path string, handler fack.Router) *fack.Route {
+	return node.FunctionContext(path, fack.Adapt(handler))
+}
+
+// FunctionContext
+// Registers a handler that receives a context.Context, the context is cancelled when the client
+// disconnects, when the Route timeout is exceeded, or when the node stops draining on Shutdown.
+//
+// The path may be a template holding {name} segments (ex. /users/{id}), the matched values are
+// read through Request.PathParam. Permissions and policies are looked up against the template.
+func (node *Node) FunctionContext(path string, handler fack.ContextRouter) *fack.Route {
+
+	// the Route returned is configured after it is registered, which is only safe before the
+	// node is running; AddFunction registers a Route that has already been configured
+	if node.status != Startup {
+		panic("Endpoints should not be added dynamically to the Node during runtime, use AddFunction")
+	}
+
+	route := fack.NewRoute(path)
+	node.router.handle(path, node.serve(route, handler))
 
-		// this exists in the event that an unintended error or unforeseen error has been improperly handled
-		// by a user-defined handler function or a packet has corrupted IP / body data
+	// the route is only registered once the router has accepted its path
+	node.mutex.Lock()
+	node.routes[path] = route
+	node.mutex.Unlock()
+
+	return route
+}
+
+// AddFunction
+// Registers a handler on the path of an already configured Route, it can be called while the
+// node is running. Returns a RouteConflictError if the path is already registered, or a
+// MalformedRouteError if its template is malformed. The Route should not be changed once it has
+// been added.
+func (node *Node) AddFunction(route *fack.Route, handler fack.ContextRouter) error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	if err := node.router.add(route.GetPath(), node.serve(route, handler)); err != nil {
+		return err
+	}
+	node.routes[route.GetPath()] = route
+
+	return nil
+}
+
+// ReplaceFunction
+// Atomically swaps the Route and handler registered on the path of the Route, requests already
+// being handled finish on the previous handler. Returns a RouteNotFoundError if the path is not
+// registered, or a MalformedRouteError if its template is malformed.
+func (node *Node) ReplaceFunction(route *fack.Route, handler fack.ContextRouter) error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	replaced, err := node.router.replace(route.GetPath(), node.serve(route, handler))
+	if err != nil {
+		return err
+	}
+	delete(node.limiters, node.routes[replaced])
+	delete(node.routes, replaced)
+	node.routes[route.GetPath()] = route
+
+	return nil
+}
+
+// RemoveFunction
+// Unregisters the path, requests already being handled finish while new requests to the path
+// are no longer served. Returns a RouteNotFoundError if the path is not registered.
+func (node *Node) RemoveFunction(path string) error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	if err := node.router.remove(path); err != nil {
+		return err
+	}
+	delete(node.limiters, node.routes[path])
+	delete(node.routes, path)
+
+	return nil
+}
+
+// serve
+// The HTTP handler of a Function, it holds onto its Route so that a request keeps the Route it
+// was matched with even if the Function is replaced while it is being handled.
+func (node *Node) serve(route *fack.Route, handler fack.ContextRouter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		// the whole wrapper is recovered, not only the middleware chain, so that a chain without
+		// the Recovery middleware (or a panic while the request is being set up) still answers with
+		// the error envelope. The status is recorded and the response sent once the panic is handled.
+		response := NewResponse()
+		request := &Request{http: r, node: node, route: route, header: w.Header(), legacyParams: node.legacyParams}
+		finish := func() {}
 		defer func() {
-			if err := recover(); err != nil {
-				response.AddStatus(http.StatusInternalServerError, "Node panic")
+			if value := recover(); value != nil {
+				node.report(request, newPanicError(value))
+				fail(response)
+			}
+			// a response the codec cannot encode is answered with a 500, the status is recorded
+			// once it is known
+			data, err := response.encode()
+			if err != nil {
+				node.report(request, err)
+			}
+			finish()
+			if err := response.write(w, data); err != nil {
+				node.logger.Log(fack.LevelError, "response could not be sent",
+					"node", node.name, "path", route.GetPath(), "error", err.Error())
 			}
 		}()
+
+		response.codec = node.responseCodec(r)
+		response.compressor, response.threshold = node.responseCompressor(r), node.threshold
+		// the encoding of every response may depend on Accept-Encoding, not only of those compressed
+		if len(node.compressors) > 0 {
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+
+		// every call carries a request ID and trace context, accepted from the client or generated,
+		// which handlers read through their context and pass on to the requests they send
+		trace := NewTrace(r)
+		r = r.WithContext(WithTrace(r.Context(), trace))
+		w.Header().Set(RequestIDHeader, trace.RequestID)
+		w.Header().Set(TraceparentHeader, trace.Traceparent())
+		response.RequestID = trace.RequestID
+		response.Traceparent = trace.Traceparent()
+		request.http = r
+		request.pathParams = pathParams(r.Context())
+
+		// recorded before the response is sent, once its status can no longer change
+		start := time.Now()
+		done := node.metrics.begin(route.GetPath(), r.Method)
+		finish = func() {
+			done(response.GetStatus())
+			node.logger.Log(route.LogLevel(), "function called",
+				"node", node.name,
+				"path", route.GetPath(),
+				"method", r.Method,
+				"sender", r.RemoteAddr,
+				"status", response.GetStatus(),
+				"duration", time.Since(start),
+				"request_id", trace.RequestID,
+				"trace_id", trace.TraceID)
+		}
+
+		// a frozen node is still alive, but does not call any function until it is running again
+		if node.GetStatus() == Frozen {
+			node.setRetryAfter(w.Header())
+			response.SetStatus(http.StatusServiceUnavailable).SetDescription(NodeFrozen)
+			return
+		}
+
+		node.mutex.Lock()
+		middleware := node.middleware
+		node.mutex.Unlock()
+
+		// requests are only admitted once they have been decoded and authenticated, a request that
+		// is rejected anyway never holds a slot or a place in the queue
+		chain(authenticated(admission(invoke(handler))), middleware, route.GetMiddleware())(request, response)
+	}
+}
+
+// Use
+// Appends middleware to the node-wide chain, every Function runs the node chain before the
+// middleware of its own Route.
+func (node *Node) Use(middleware ...fack.Middleware) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	node.middleware = append(node.middleware, middleware...)
+}
+
+// Middleware
+// Replaces the node-wide chain, including the defaults returned by DefaultMiddleware. Passing a
+// reordered or reduced copy of the defaults changes how (or if) the built-in checks are applied,
+// except for Authentication which is always applied before the handler of a protected route.
+func (node *Node) Middleware(middleware ...fack.Middleware) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	node.middleware = middleware
+}
+
+// Routes
+// Returns every route registered on the node sorted by path.
+func (node *Node) Routes() []*fack.Route {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	routes := make([]*fack.Route, 0, len(node.routes))
+	for _, route := range node.routes {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].GetPath() < routes[j].GetPath()
 	})
 
-	return route
+	return routes
+}
+
+// DrainTimeout
+// The deadline Shutdown gives in-flight handlers to finish before their connections are closed.
+func (node *Node) DrainTimeout(timeout time.Duration) {
+	if node.status == Startup {
+		node.drainTimeout = timeout
+	}
+}
+
+func (node *Node) GetStatus() NodeStatus {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	return node.status
 }
 
-func (node *Node) Start() {
-	node.Status(Running) // thread safe
+// Start
+// Blocks while the node serves requests. Returns nil once the node has been shut down, or the
+// error that stopped the HTTP server from listening or serving.
+func (node *Node) Start() error {
+	node.mutex.Lock()
+	if node.status != Startup {
+		node.mutex.Unlock()
+		return &fack.NodeIllegalActionError{}
+	}
+	node.status = Running
+
+	node.server.Addr = node.address.ToString()
+	node.server.Handler = node.router
+	node.server.BaseContext = func(net.Listener) context.Context {
+		// every request context is derived from the node, cancelling it cancels every handler
+		return node.ctx
+	}
+	node.mutex.Unlock()
+
+	node.logger.Log(fack.LevelInfo, "http node started", "node", node.name, "address", node.address.ToString())
+
+	if (node.development != nil) && !node.IsProduction() {
+		node.logger.Log(fack.LevelWarn, "development policy enabled; authentication is bypassed",
+			"node", node.name, "sources", strings.Join(node.development.Sources(), ","))
+	}
 
-	log.Printf("(!) http node started on %s\n", node.address.ToString())
+	err := node.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
 
-	http.ListenAndServe(node.address.ToString(), node.mux)
+	node.Status(Killed)
+	return err
 }
 
-func (node *Node) Shutdown() {
-	node.Status(Killed) // TODO - kill HTTP server
+// Shutdown
+// Stops accepting new connections and waits up to the drain timeout for in-flight handlers to
+// finish, after which the context of any remaining handler is cancelled and its connection is
+// closed. The node is Killed either way.
+func (node *Node) Shutdown() error {
+	if node.GetStatus() == Killed {
+		return &fack.NodeIllegalActionError{}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), node.drainTimeout)
+	defer cancel()
+
+	err := node.server.Shutdown(ctx)
+	if err != nil {
+		// the deadline passed before every handler finished, signal them through their
+		// context and stop waiting on them
+		node.cancel()
+		node.server.Close()
+	}
+	node.cancel()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer func() {
-		// extra handling here
-		cancel()
-	}()
+	node.Status(Killed)
+	node.logger.Log(fack.LevelInfo, "http node shutdown", "node", node.name)
 
-	node.server.Shutdown(ctx)
+	return err
 }
 
-func (node Node) ToString() string {
-	j, _ := json.Marshal(node)
+func (node *Node) ToString() string {
+	j, _ := json.Marshal(struct {
+		Name    string        `json:"name"`
+		Address *fack.Address `json:"address"`
+		Status  NodeStatus    `json:"status"`
+	}{node.name, node.address, node.status})
 	return string(j)
 }
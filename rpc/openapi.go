@@ -0,0 +1,387 @@
+package rpc
+
+import (
+	"encoding/json"
+	"github.com/GabeCordo/fack"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	OpenAPIVersion      = "3.0.3"
+	OpenAPIPath         = "/.well-known/openapi.json"
+	SignatureScheme     = "ecdsaSignature"
+	MultiSignatureField = "x-fack-multisig"
+	SignatureField      = "x-fack-signature"
+	schemaReference     = "#/components/schemas/"
+)
+
+var operationReplacer = strings.NewReplacer("/", "_", "{", "", "}", "")
+
+// Schema
+// A JSON Schema object as embedded in an OpenAPI document.
+type Schema map[string]any
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIMediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+type OpenAPIParameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   Schema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	MultiSig    *fack.MultiSignature       `json:"x-fack-multisig,omitempty"`
+	Timeout     string                     `json:"x-fack-timeout,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]Schema `json:"schemas"`
+	SecuritySchemes map[string]Schema `json:"securitySchemes"`
+}
+
+// OpenAPIDocument
+// An OpenAPI 3 description of the Functions registered with a node. Paths map to the operations
+// of each supported HTTP method, PULL is not an OpenAPI method and is listed as "x-pull".
+type OpenAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       OpenAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                      `json:"components"`
+}
+
+// OpenAPI
+// Generates the document from the routes registered with the node. Routes registered through
+// Handle, or given a Schema, document the arguments and result of their handler; any other
+// route documents the generic Request and Response envelopes.
+func (node *Node) OpenAPI(version string) OpenAPIDocument {
+	document := OpenAPIDocument{
+		OpenAPI:    OpenAPIVersion,
+		Info:       OpenAPIInfo{Title: node.name, Version: version},
+		Paths:      make(map[string]map[string]OpenAPIOperation),
+		Components: envelopeComponents(),
+	}
+
+	for _, route := range node.Routes() {
+		operations := make(map[string]OpenAPIOperation)
+		for _, method := range route.SupportedMethods() {
+			operations[openAPIMethod(method)] = describeOperation(route, method)
+		}
+		document.Paths[route.GetPath()] = operations
+	}
+
+	return document
+}
+
+// ServeOpenAPI
+// Serves the document at OpenAPIPath. The document is not wrapped in a Response and is served to
+// any client without authentication, it is generated on every request so it always matches the
+// routes of the node.
+func (node *Node) ServeOpenAPI(version string) {
+	if node.status != Startup {
+		panic("Endpoints should not be added dynamically to the Node during runtime")
+	}
+
+	node.router.handle(OpenAPIPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(node.OpenAPI(version))
+	})
+}
+
+func openAPIMethod(method fack.HTTPMethod) string {
+	if method == fack.PULL {
+		return "x-pull"
+	}
+	return strings.ToLower(method.ToString())
+}
+
+func describeOperation(route *fack.Route, method fack.HTTPMethod) OpenAPIOperation {
+	operation := OpenAPIOperation{
+		OperationID: strings.ToLower(method.ToString()) + operationReplacer.Replace(route.GetPath()),
+		Summary:     route.GetDescription(),
+		Responses: map[string]OpenAPIResponse{
+			"200": jsonResponse("Function succeeded", responseSchema(route.OutputType())),
+			"400": jsonResponse("Malformed request or arguments", reference("Response")),
+			"500": jsonResponse("Function failed", reference("Response")),
+		},
+		MultiSig: route.MultiSigRequirement(),
+	}
+
+	// template paths already use the OpenAPI {name} syntax
+	if isTemplate(route.GetPath()) {
+		// the path of a registered route has already been parsed
+		segments, _ := parseTemplate(route.GetPath())
+		for _, segment := range segments {
+			if strings.HasPrefix(segment, "{") {
+				operation.Parameters = append(operation.Parameters, OpenAPIParameter{
+					Name:     segment[1 : len(segment)-1],
+					In:       "path",
+					Required: true,
+					Schema:   Schema{"type": "string"},
+				})
+			}
+		}
+	}
+
+	operation.RequestBody = &OpenAPIRequestBody{
+		Required: true,
+		Content:  map[string]OpenAPIMediaType{"application/json": {Schema: requestSchema(route.InputType())}},
+	}
+
+	if route.RequiresAuth || (route.MultiSigRequirement() != nil) {
+		operation.Security = []map[string][]string{{SignatureScheme: {}}}
+		operation.Responses["401"] = jsonResponse("Sender is not authorized", reference("Response"))
+	}
+
+	if route.GetTimeout() > 0 {
+		operation.Timeout = route.GetTimeout().String()
+		operation.Responses["504"] = jsonResponse("Function deadline exceeded", reference("Response"))
+	}
+
+	return operation
+}
+
+func jsonResponse(description string, schema Schema) OpenAPIResponse {
+	return OpenAPIResponse{
+		Description: description,
+		Content:     map[string]OpenAPIMediaType{"application/json": {Schema: schema}},
+	}
+}
+
+func reference(name string) Schema {
+	return Schema{"$ref": schemaReference + name}
+}
+
+// requestSchema
+// A struct input accepts named arguments matching its fields, or positional arguments in field
+// order. A slice accepts every positional argument and any other input accepts exactly one.
+func requestSchema(input reflect.Type) Schema {
+	if input == nil {
+		return reference("Request")
+	}
+
+	var args Schema
+	switch input.Kind() {
+	case reflect.Struct:
+		args = Schema{"oneOf": []Schema{
+			typeSchema(input, true, make(map[reflect.Type]bool)),
+			{"type": "array", "description": "positional arguments in field order"},
+		}}
+	case reflect.Slice, reflect.Array:
+		args = typeSchema(input, true, make(map[reflect.Type]bool))
+	default:
+		args = Schema{"type": "array", "items": typeSchema(input, true, make(map[reflect.Type]bool)), "minItems": 1, "maxItems": 1}
+	}
+
+	return Schema{"allOf": []Schema{
+		reference("Request"),
+		{"type": "object", "properties": Schema{"args": args}},
+	}}
+}
+
+// responseSchema
+// Mirrors encodeResult, a struct or map output is flattened into the data of the Response and
+// any other output is stored under ResultKey.
+func responseSchema(output reflect.Type) Schema {
+	if output == nil {
+		return reference("Response")
+	}
+
+	for output.Kind() == reflect.Pointer {
+		output = output.Elem()
+	}
+
+	data := typeSchema(output, false, make(map[reflect.Type]bool))
+	if (output.Kind() != reflect.Struct) && (output.Kind() != reflect.Map) {
+		data = Schema{"type": "object", "properties": Schema{ResultKey: data}}
+	}
+
+	return Schema{"allOf": []Schema{
+		reference("Response"),
+		{"type": "object", "properties": Schema{"data": data}},
+	}}
+}
+
+// typeSchema
+// Builds the JSON Schema of a Go type following its JSON encoding. Struct fields of an input are
+// required unless tagged optional, fields of an output are required unless tagged omitempty.
+// A recursive type is described as an unconstrained schema where it refers to itself.
+func typeSchema(t reflect.Type, input bool, visiting map[reflect.Type]bool) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "format": "byte"}
+		}
+		return Schema{"type": "array", "items": typeSchema(t.Elem(), input, visiting)}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": typeSchema(t.Elem(), input, visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return Schema{}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := Schema{}
+		required := make([]string, 0)
+		structSchema(t, input, visiting, properties, &required)
+
+		schema := Schema{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	default:
+		return Schema{}
+	}
+}
+
+func structSchema(t reflect.Type, input bool, visiting map[reflect.Type]bool, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || (input && (field.Tag.Get(paramTag) == paramSkip)) {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		// embedded structs are flattened into the parent by their JSON encoding
+		if field.Anonymous && (len(tag[0]) == 0) && (field.Type.Kind() == reflect.Struct) {
+			structSchema(field.Type, input, visiting, properties, required)
+			continue
+		}
+
+		name := field.Name
+		if len(tag[0]) > 0 {
+			name = tag[0]
+		}
+		properties[name] = typeSchema(field.Type, input, visiting)
+
+		optional := strings.Contains(strings.Join(tag[1:], ","), "omitempty")
+		if input {
+			optional = strings.Contains(field.Tag.Get(paramTag), paramOptional)
+		}
+		if !optional {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// envelopeComponents
+// The wire format shared by every Function, and the signature scheme that authenticates it.
+func envelopeComponents() OpenAPIComponents {
+	return OpenAPIComponents{
+		Schemas: map[string]Schema{
+			"Request": {
+				"type":     "object",
+				"required": []string{"function"},
+				"properties": Schema{
+					"function": Schema{"type": "string"},
+					"param":    Schema{"type": "array", "items": Schema{"type": "string"}, "deprecated": true},
+					"args": Schema{"oneOf": []Schema{
+						{"type": "array"},
+						{"type": "object"},
+					}},
+					"auth": reference("Auth"),
+				},
+			},
+			"Auth": {
+				"type": "object",
+				"properties": Schema{
+					"signature":  Schema{"type": "string", "format": "byte"},
+					"nonce":      Schema{"type": "integer", "format": "int64"},
+					"signatures": Schema{"type": "array", "items": reference("Signature")},
+				},
+			},
+			"Signature": {
+				"type":     "object",
+				"required": []string{"endpoint", "nonce", "signature"},
+				"properties": Schema{
+					"endpoint":  Schema{"type": "string"},
+					"nonce":     Schema{"type": "integer", "format": "int64"},
+					"signature": Schema{"type": "string", "format": "byte"},
+				},
+			},
+			"Response": {
+				"type":     "object",
+				"required": []string{"status"},
+				"properties": Schema{
+					"status":      Schema{"type": "integer"},
+					"description": Schema{"type": "string"},
+					"data":        Schema{"type": "object", "additionalProperties": true},
+				},
+			},
+		},
+		SecuritySchemes: map[string]Schema{
+			// an apiKey can only be placed in a header, query or cookie, the signature is carried in the
+			// body of the Request envelope instead, which the x-fack-signature extension describes
+			SignatureScheme: {
+				"type": "apiKey",
+				"in":   "header",
+				"name": "auth.signature",
+				"description": "ECDSA (P-256, ASN.1) signature of the SHA-256 request hash carried in the body as " +
+					"auth.signature alongside auth.nonce, not in a header. Routes listing " + MultiSignatureField +
+					" additionally require the threshold of named signers in auth.signatures.",
+				SignatureField: Schema{
+					"in":         "body",
+					"signature":  "auth.signature",
+					"nonce":      "auth.nonce",
+					"signatures": "auth.signatures",
+					"algorithm":  "ECDSA P-256 ASN.1",
+					"hash":       "SHA-256",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"net/http"
+	"runtime/debug"
+)
+
+const (
+	NodePanic = "Node panic"
+)
+
+// PanicError
+// A panic recovered from a Function, along with the stack of the goroutine that panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// newPanicError
+// Must be called from the deferred function that recovered the value, so that the stack is the
+// stack of the panic. A PanicError re-raised from another goroutine keeps its original stack.
+func newPanicError(value any) *PanicError {
+	if err, ok := value.(*PanicError); ok {
+		return err
+	}
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+// ErrorHook
+// Called with every panic recovered from a Function (as a *PanicError) and every error a typed
+// handler returns that is not an *Error, for reporting to an external service.
+type ErrorHook func(request fack.Request, err error)
+
+// OnError
+// Sets the hook errors are reported to, it can be changed while the node is running.
+func (node *Node) OnError(hook ErrorHook) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	node.errorHook = hook
+}
+
+// report
+// Logs the error and passes it to the error hook, a panicking hook cannot take down the request.
+func (node *Node) report(request fack.Request, err error) {
+	fields := []any{"node", node.name, "request_id", request.RequestID(), "error", err.Error()}
+	if r, ok := request.(*Request); ok && (r.Route() != nil) {
+		fields = append(fields, "path", r.Route().GetPath())
+	}
+	if p, ok := err.(*PanicError); ok {
+		fields = append(fields, "stack", string(p.Stack))
+	}
+	node.logger.Log(fack.LevelError, "function failed", fields...)
+
+	node.mutex.Lock()
+	hook := node.errorHook
+	node.mutex.Unlock()
+
+	if hook == nil {
+		return
+	}
+
+	defer func() {
+		if value := recover(); value != nil {
+			node.logger.Log(fack.LevelError, "error hook panicked", "node", node.name, "error", fmt.Sprint(value))
+		}
+	}()
+	hook(request, err)
+}
+
+// fail
+// Replaces anything the Function wrote to the response with the error envelope, the client is
+// never sent the panic value or the partial data of the Function.
+func fail(response fack.Response) {
+	data := response.GetData()
+	for key := range data {
+		delete(data, key)
+	}
+	response.SetStatus(http.StatusInternalServerError).SetDescription(NodePanic)
+}
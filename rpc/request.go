@@ -2,13 +2,16 @@ package rpc
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"github.com/GabeCordo/fack"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,12 +21,37 @@ const (
 )
 
 type Request struct {
-	Function string   `json:"function"`
-	Param    []string `json:"param,omitempty"`
+	Function string     `json:"function"`
+	Param    []string   `json:"param,omitempty"`
+	Args     *Arguments `json:"args,omitempty"`
 	Auth     struct {
-		Signature []byte `json:"signature,omitempty"`
-		Nonce     int64  `json:"nonce,omitempty"`
+		Signature  []byte           `json:"signature,omitempty"`
+		Nonce      int64            `json:"nonce,omitempty"`
+		Signatures []fack.Signature `json:"signatures,omitempty"`
 	} `json:"auth,omitempty"`
+
+	// only populated for requests received by a Node
+	http        *http.Request
+	node        *Node
+	route       *fack.Route
+	pathParams  map[string]string
+	ctx         context.Context
+	codec       Codec
+	compressors []Compressor
+
+	// set once Authentication has accepted the request, endpoint is only set when Authentication
+	// verified the signature of the sender
+	authenticated bool
+	endpoint      *fack.Endpoint
+
+	// set when the node accepts signatures over the hash of clients that predate args
+	legacyParams bool
+
+	// frees the admission slots held by the request
+	release func()
+
+	// the headers of the HTTP response, written before the Response is sent
+	header http.Header
 }
 
 func NewRequest(function string) *Request {
@@ -38,6 +66,120 @@ func (r Request) GetEndpoint() string {
 	return r.Function
 }
 
+// GetParams
+// The legacy string params of the request. Requests sent with args return the string form of
+// their positional args instead so that handlers and policies written against params keep
+// working, args take precedence over params everywhere they are read.
+func (r Request) GetParams() []string {
+	if !r.Args.isSet() {
+		return r.Param
+	}
+
+	params := make([]string, len(r.Args.Positional))
+	for i, value := range r.Args.Positional {
+		if s, err := r.Arg(i).String(); err == nil {
+			params[i] = s
+		} else {
+			data, _ := json.Marshal(value)
+			params[i] = string(data)
+		}
+	}
+	return params
+}
+
+// PathParam
+// The value matched by the {name} segment of the route template, empty if there is none.
+func (r Request) PathParam(name string) string {
+	return r.pathParams[name]
+}
+
+func (r Request) PathParams() map[string]string {
+	return r.pathParams
+}
+
+// AddArg
+// Appends a positional argument, a request cannot hold both positional and named arguments.
+func (r *Request) AddArg(value any) *Request {
+	if r.Args == nil {
+		r.Args = new(Arguments)
+	}
+	if r.Args.Named != nil {
+		panic("positional arguments cannot be added to a request with named arguments")
+	}
+	r.Args.Positional = append(r.Args.Positional, value)
+
+	return r
+}
+
+// SetArg
+// Sets a named argument, a request cannot hold both positional and named arguments.
+func (r *Request) SetArg(name string, value any) *Request {
+	if r.Args == nil {
+		r.Args = new(Arguments)
+	}
+	if len(r.Args.Positional) > 0 {
+		panic("named arguments cannot be added to a request with positional arguments")
+	}
+	if r.Args.Named == nil {
+		r.Args.Named = make(map[string]any)
+	}
+	r.Args.Named[name] = value
+
+	return r
+}
+
+// Arg
+// The positional argument at the index, falling back to the legacy string params for
+// clients that do not send structured arguments.
+func (r Request) Arg(index int) fack.Argument {
+	if (r.Args != nil) && (index >= 0) && (index < len(r.Args.Positional)) {
+		return Argument{value: r.Args.Positional[index], exists: true}
+	}
+	if !r.Args.isSet() && (index >= 0) && (index < len(r.Param)) {
+		return Argument{value: r.Param[index], exists: true}
+	}
+	return Argument{}
+}
+
+func (r Request) NamedArg(name string) fack.Argument {
+	if (r.Args != nil) && (r.Args.Named != nil) {
+		if value, found := r.Args.Named[name]; found {
+			return Argument{value: value, exists: true}
+		}
+	}
+	return Argument{}
+}
+
+// ArgCount
+// The number of positional arguments, or legacy params, held by the request.
+func (r Request) ArgCount() int {
+	if r.Args.isSet() {
+		return len(r.Args.Positional)
+	}
+	return len(r.Param)
+}
+
+// DecodeArgs
+// Decodes the arguments into v through their JSON encoding, named arguments decode into a
+// struct or map and positional arguments (or legacy params) decode into a slice.
+func (r Request) DecodeArgs(v any) error {
+	var data []byte
+	var err error
+	if r.Args.isSet() {
+		data, err = json.Marshal(r.Args)
+	} else {
+		data, err = json.Marshal(r.Param)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.New(MismatchArgument)
+	}
+	return nil
+}
+
 func (r Request) Bytes() []byte {
 	byteData, err := json.Marshal(r)
 	if err != nil {
@@ -47,12 +189,48 @@ func (r Request) Bytes() []byte {
 }
 
 func (r Request) GetHash() []byte {
-	concatenatedString := r.Function + strconv.FormatInt(r.Auth.Nonce, Decimal)
+	return r.GetHashWithNonce(r.Auth.Nonce)
+}
+
+// GetHashWithNonce
+// Co-signers of a multi-signature request each sign the hash generated with their own nonce.
+// Structured arguments are covered by the hash through their canonical encoding and legacy
+// params through their JSON encoding, under a separate prefix so one cannot stand in for the other.
+func (r Request) GetHashWithNonce(nonce int64) []byte {
+	concatenatedString := r.Function + strconv.FormatInt(nonce, Decimal)
+	if r.Args.isSet() {
+		canonical, err := r.Args.Canonical()
+		if err != nil {
+			// an argument that cannot be encoded can never produce a matching signature
+			return nil
+		}
+		concatenatedString += ":" + string(canonical)
+	}
+	if len(r.Param) > 0 {
+		params, err := json.Marshal(r.Param)
+		if err != nil {
+			return nil
+		}
+		concatenatedString += ":param:" + string(params)
+	}
 	bit32ShaBytes := sha256.Sum256([]byte(concatenatedString))
 
 	return bit32ShaBytes[:]
 }
 
+// GetLegacyHash
+// The hash signed by clients that predate structured arguments, it only covers the function and
+// the nonce. It is only returned for requests without args received by a node that enabled
+// LegacyParamSignatures, as the params it leaves out can be altered in transit.
+func (r Request) GetLegacyHash() ([]byte, bool) {
+	if !r.legacyParams || r.Args.isSet() {
+		return nil, false
+	}
+
+	bit32ShaBytes := sha256.Sum256([]byte(r.Function + strconv.FormatInt(r.Auth.Nonce, Decimal)))
+	return bit32ShaBytes[:], true
+}
+
 func (r Request) GetNonce() int64 {
 	return r.Auth.Nonce
 }
@@ -69,22 +247,137 @@ func (r *Request) SetSignature(bytes []byte) {
 	r.Auth.Signature = bytes
 }
 
+func (r Request) GetSignatures() []fack.Signature {
+	return r.Auth.Signatures
+}
+
+func (r *Request) AddSignature(signature fack.Signature) {
+	// a co-signer that signs again replaces its previous signature
+	for i, s := range r.Auth.Signatures {
+		if s.Endpoint == signature.Endpoint {
+			r.Auth.Signatures[i] = signature
+			return
+		}
+	}
+	r.Auth.Signatures = append(r.Auth.Signatures, signature)
+}
+
+// Context
+// The context of the HTTP request the Request was received through, it is cancelled when the
+// client disconnects or the node is shutdown. Requests that were not received by a Node use
+// the background context.
+func (r Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+	if r.http != nil {
+		return r.http.Context()
+	}
+	return context.Background()
+}
+
+// HTTP
+// The HTTP request the Request was received through, nil for requests that were not received by a Node.
+func (r Request) HTTP() *http.Request {
+	return r.http
+}
+
+// Route
+// The Route the Request was received on, nil for requests that were not received by a Node.
+func (r Request) Route() *fack.Route {
+	return r.route
+}
+
 // rpc methods
 
+// AcceptEncoding
+// The compressors the response may be compressed with, in order of preference. Requests accept
+// gzip unless other compressors (or none at all) are given.
+func (r *Request) AcceptEncoding(compressors ...Compressor) *Request {
+	r.compressors = append([]Compressor{}, compressors...)
+
+	return r
+}
+
+// Codec
+// Sends the request with the codec, the node answers with the same codec. Requests are sent as
+// JSON unless another codec is chosen.
+func (r *Request) Codec(codec Codec) *Request {
+	r.codec = codec
+
+	return r
+}
+
+// WithContext
+// Sends the request with the context, a request sent with the context of a handler carries on
+// its request ID and trace context. See Call for requests sent from within a handler.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+
+	return r
+}
+
+// SendContext
+// Identical to Send, the request is cancelled with the context and propagates its trace.
+func (r *Request) SendContext(ctx context.Context, method, url string) (*Response, error) {
+	return r.WithContext(ctx).Send(method, url)
+}
+
+// Call
+// A request to a function sent on behalf of the request received by the node, it inherits the
+// context of the handler so that it carries on its request ID and traceparent, and is cancelled
+// with it.
+func (r *Request) Call(function string) *Request {
+	return NewRequest(function).WithContext(r.Context())
+}
+
+// RequestID
+// The ID of a request received by a Node, either sent by the client or generated by the Node.
+func (r Request) RequestID() string {
+	trace, _ := TraceFromContext(r.Context())
+	return trace.RequestID
+}
+
+// Trace
+// The request ID and trace context of a request received by a Node.
+func (r Request) Trace() Trace {
+	trace, _ := TraceFromContext(r.Context())
+	return trace
+}
+
 func (r Request) Send(method, url string) (*Response, error) {
 	httpClient := http.Client{Timeout: StandardTimeout}
 
 	httpUrl := url + r.Function
-	log.Println(httpUrl)
-	httpRequest, err := http.NewRequest(method, httpUrl, nil)
+	httpRequest, err := http.NewRequestWithContext(r.Context(), method, httpUrl, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// the server will only accept core provided with the application/json Content-Type
-	// header, otherwise the request will be rejected
-	httpRequest.Header.Add("Content-Type", "application/json")
-	httpRequest.Body = io.NopCloser(bytes.NewReader(r.Bytes()))
+	codec := r.codec
+	if codec == nil {
+		codec = JSON
+	}
+
+	body, err := codec.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// the node rejects a body whose Content-Type it has no codec for, and answers with the
+	// codec the client accepts
+	httpRequest.Header.Set("Content-Type", codec.ContentType())
+	httpRequest.Header.Set("Accept", codec.ContentType())
+
+	compressors := r.compressors
+	if compressors == nil {
+		compressors = []Compressor{Gzip}
+	}
+	if len(compressors) > 0 {
+		httpRequest.Header.Set("Accept-Encoding", acceptEncoding(compressors))
+	}
+	propagate(r.Context(), httpRequest)
+	httpRequest.Body = io.NopCloser(bytes.NewReader(body))
 
 	// note -> any auth should be done before this function call
 	resp, err := httpClient.Do(httpRequest)
@@ -93,18 +386,42 @@ func (r Request) Send(method, url string) (*Response, error) {
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	// setting Accept-Encoding turns off the transparent gzip of the transport, so the response is
+	// decompressed here with the compressor it names
+	reader := io.Reader(resp.Body)
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" {
+		compressor := findCompressor(compressors, encoding)
+		if compressor == nil {
+			return nil, errors.New("the response was compressed with an encoding that was not accepted")
+		}
+		decompressed, err := compressor.Decompress(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer decompressed.Close()
+		reader = decompressed
+	}
+
+	body, err = ioutil.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
 
-	// the HTTP data will contain and EOF header at the start of the response
-	// body that needs to be stripped before we can unmarshal the JSON content
-	strBody := string(body)
+	// the node answers with the codec of the request when it accepts it, which may be a codec the
+	// client only knows through the request
+	contentType := resp.Header.Get("Content-Type")
+	responseCodec, found := lookupCodec(map[string]Codec{strings.ToLower(codec.ContentType()): codec}, contentType)
+	if !found {
+		responseCodec, found = lookupCodec(clientCodecs, contentType)
+	}
+	if !found {
+		responseCodec = JSON
+	}
 
-	// Unmarshal or Decode the JSON to the frontend.
 	result := new(Response)
-	json.Unmarshal([]byte(strBody), &result)
+	if err := responseCodec.Unmarshal(body, result); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
@@ -1,7 +1,6 @@
 package rpc
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/GabeCordo/fack"
 	"net/http"
@@ -19,6 +18,15 @@ type Response struct {
 	Status      int               `json:"status"`
 	Description string            `json:"description,omitempty"`
 	Data        fack.ResponseData `json:"data,omitempty"`
+	RequestID   string            `json:"requestId,omitempty"`
+	Traceparent string            `json:"traceparent,omitempty"`
+
+	// the codec the response is sent with, JSON unless the client accepts another
+	codec Codec
+
+	// responses of at least threshold bytes are compressed when the client accepts an encoding
+	compressor Compressor
+	threshold  int
 }
 
 func NewResponse() *Response {
@@ -76,11 +84,51 @@ func (r *Response) AddStatus(httpResponseCode int, message ...string) {
 	}
 }
 
-func (r *Response) Send(w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json")
+// Send
+// Writes the response to the client, returns the error if the response could not be encoded.
+func (r *Response) Send(w http.ResponseWriter) error {
+	data, err := r.encode()
+	if writeErr := r.write(w, data); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// encode
+// Marshals the response with its codec. A response that cannot be marshalled is replaced with a
+// 500 holding the Failure description and no data, the client is never sent a success without
+// the data it was promised.
+func (r *Response) encode() ([]byte, error) {
+	data, err := r.getCodec().Marshal(r)
+	if err == nil {
+		return data, nil
+	}
+
+	for key := range r.Data {
+		delete(r.Data, key)
+	}
+	r.SetStatus(http.StatusInternalServerError).SetDescription(Failure)
+
+	data, _ = r.getCodec().Marshal(&Response{Status: r.Status, Description: r.Description, RequestID: r.RequestID, Traceparent: r.Traceparent})
+	return data, err
+}
+
+func (r *Response) write(w http.ResponseWriter, data []byte) error {
+	w.Header().Set("Content-Type", r.getCodec().ContentType())
+	if (r.compressor != nil) && (len(data) >= r.threshold) {
+		if compressed, err := r.compressor.Compress(data); err == nil {
+			w.Header().Set("Content-Encoding", r.compressor.Encoding())
+			data = compressed
+		}
+	}
 	w.WriteHeader(r.Status)
-	err := json.NewEncoder(w).Encode(r)
-	if err != nil {
-		fmt.Println(err.Error())
+	_, err := w.Write(data)
+	return err
+}
+
+func (r *Response) getCodec() Codec {
+	if r.codec == nil {
+		return JSON
 	}
+	return r.codec
 }
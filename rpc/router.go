@@ -0,0 +1,290 @@
+package rpc
+
+import (
+	"context"
+	"github.com/GabeCordo/fack"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// router
+// Dispatches requests to the handlers registered with the node. A path is matched exactly first,
+// then against the route templates (ex. /users/{id}), and finally against the paths ending in a
+// slash which, like http.ServeMux, match every path below them.
+type router struct {
+	exact     map[string]http.HandlerFunc
+	templates []*template
+	subtrees  []string
+	mutex     sync.RWMutex
+}
+
+// template
+// A route path holding one or more {name} segments, each matching a single non-empty segment.
+type template struct {
+	path     string
+	segments []string
+	handler  http.HandlerFunc
+}
+
+func newRouter() *router {
+	router := new(router)
+	router.exact = make(map[string]http.HandlerFunc)
+	router.templates = make([]*template, 0)
+	router.subtrees = make([]string, 0)
+
+	return router
+}
+
+func isTemplate(path string) bool {
+	return strings.ContainsAny(path, "{}")
+}
+
+// parseTemplate
+// Returns a MalformedRouteError if a segment is not either a literal or a single {name}, or a
+// name is used twice.
+func parseTemplate(path string) ([]string, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	names := make(map[string]bool)
+
+	for _, segment := range segments {
+		if !strings.ContainsAny(segment, "{}") {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(segment, "{"), "}")
+		if (len(name)+2 != len(segment)) || (len(name) == 0) || strings.ContainsAny(name, "{}") {
+			return nil, &fack.MalformedRouteError{Path: path, Reason: "malformed path parameter " + segment}
+		}
+		if names[name] {
+			return nil, &fack.MalformedRouteError{Path: path, Reason: "path parameter " + segment + " is used more than once"}
+		}
+		names[name] = true
+	}
+
+	return segments, nil
+}
+
+// shape
+// Two templates with the same shape (ex. /users/{id} and /users/{name}) can never be told apart.
+func shape(segments []string) string {
+	shaped := make([]string, len(segments))
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") {
+			shaped[i] = "{}"
+		} else {
+			shaped[i] = segment
+		}
+	}
+	return strings.Join(shaped, "/")
+}
+
+// handle
+// Registers the handler on the path, panics if the path is malformed or the path (or the shape
+// of the template) is already registered.
+func (router *router) handle(path string, handler http.HandlerFunc) {
+	if err := router.add(path, handler); err != nil {
+		panic(err.Error())
+	}
+}
+
+// add
+// Registers the handler on a path that is not yet registered.
+func (router *router) add(path string, handler http.HandlerFunc) error {
+	segments, err := parseTemplate(path)
+	if err != nil {
+		return err
+	}
+
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	if existing, found := router.lookup(path); found {
+		return &fack.RouteConflictError{Path: path, Existing: existing}
+	}
+
+	if isTemplate(path) {
+		router.templates = append(router.templates, &template{path: path, segments: segments, handler: handler})
+		// literal segments take precedence over parameters, so /users/me is matched before /users/{id}
+		sort.SliceStable(router.templates, func(i, j int) bool {
+			return moreSpecific(router.templates[i].segments, router.templates[j].segments)
+		})
+		return nil
+	}
+
+	router.exact[path] = handler
+	if strings.HasSuffix(path, "/") {
+		router.subtrees = append(router.subtrees, path)
+		// the longest subtree is the closest match
+		sort.Slice(router.subtrees, func(i, j int) bool {
+			return len(router.subtrees[i]) > len(router.subtrees[j])
+		})
+	}
+
+	return nil
+}
+
+// replace
+// Swaps the handler of a registered path, requests already matched to the previous handler
+// finish on it. Returns the path that was replaced, a template may be replaced by another of
+// the same shape (ex. /users/{id} by /users/{name}).
+func (router *router) replace(path string, handler http.HandlerFunc) (string, error) {
+	segments, err := parseTemplate(path)
+	if err != nil {
+		return "", err
+	}
+
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	existing, found := router.lookup(path)
+	if !found {
+		return "", &fack.RouteNotFoundError{Path: path}
+	}
+
+	if isTemplate(path) {
+		for i, registered := range router.templates {
+			if registered.path == existing {
+				router.templates[i] = &template{path: path, segments: segments, handler: handler}
+			}
+		}
+		return existing, nil
+	}
+
+	router.exact[path] = handler
+	return existing, nil
+}
+
+// remove
+// Unregisters the path, requests already matched to its handler finish on it.
+func (router *router) remove(path string) error {
+	router.mutex.Lock()
+	defer router.mutex.Unlock()
+
+	if existing, found := router.lookup(path); !found || (existing != path) {
+		return &fack.RouteNotFoundError{Path: path}
+	}
+
+	if isTemplate(path) {
+		for i, registered := range router.templates {
+			if registered.path == path {
+				router.templates = append(router.templates[:i], router.templates[i+1:]...)
+				break
+			}
+		}
+		return nil
+	}
+
+	delete(router.exact, path)
+	for i, subtree := range router.subtrees {
+		if subtree == path {
+			router.subtrees = append(router.subtrees[:i], router.subtrees[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// lookup
+// The registered path that the path would collide with, templates collide when they share a
+// shape. Must be called while holding the router lock.
+func (router *router) lookup(path string) (string, bool) {
+	if !isTemplate(path) {
+		_, found := router.exact[path]
+		return path, found
+	}
+
+	segments, err := parseTemplate(path)
+	if err != nil {
+		// a malformed template was never registered
+		return "", false
+	}
+	for _, existing := range router.templates {
+		if shape(existing.segments) == shape(segments) {
+			return existing.path, true
+		}
+	}
+	return "", false
+}
+
+func moreSpecific(a, b []string) bool {
+	for i := 0; (i < len(a)) && (i < len(b)); i++ {
+		aParam, bParam := strings.HasPrefix(a[i], "{"), strings.HasPrefix(b[i], "{")
+		if aParam != bParam {
+			return !aParam
+		}
+	}
+	return false
+}
+
+// match
+// Returns the path the handler was registered under, the handler and the values of any path
+// parameters. The handler is nil if no registered path matches.
+func (router *router) match(path string) (string, http.HandlerFunc, map[string]string) {
+	router.mutex.RLock()
+	defer router.mutex.RUnlock()
+
+	if handler, found := router.exact[path]; found {
+		return path, handler, nil
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, template := range router.templates {
+		if params, ok := template.match(segments); ok {
+			return template.path, template.handler, params
+		}
+	}
+
+	for _, subtree := range router.subtrees {
+		if strings.HasPrefix(path, subtree) {
+			return subtree, router.exact[subtree], nil
+		}
+	}
+
+	return "", nil, nil
+}
+
+func (template *template) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(template.segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range template.segments {
+		if strings.HasPrefix(segment, "{") {
+			if len(segments[i]) == 0 {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+func (router *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, handler, params := router.match(r.URL.Path)
+	if handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if params != nil {
+		r = r.WithContext(withPathParams(r.Context(), params))
+	}
+	handler(w, r)
+}
+
+type pathParamsKey struct{}
+
+func withPathParams(ctx context.Context, params map[string]string) context.Context {
+	return context.WithValue(ctx, pathParamsKey{}, params)
+}
+
+func pathParams(ctx context.Context) map[string]string {
+	params, _ := ctx.Value(pathParamsKey{}).(map[string]string)
+	return params
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	ExplainPort = 8133
+)
+
+func TestEffectiveAccessReport(t *testing.T) {
+	auth := fack.NewAuth()
+	endpoint := fack.NewEndpoint("reports", nil)
+	endpoint.AddGlobalPermission(fack.NewPermission().Enable(fack.GET))
+	endpoint.AddLocalPermission("/export", fack.NewPermission().Enable(fack.GET).Enable(fack.POST))
+	auth.AddTrusted("10.0.0.7", endpoint)
+
+	node := rpc.NewNode(fack.LocalHost().SetPort(8100), auth)
+	node.Function("/", index).Method(fack.GET)
+	node.Function("/export", index).Method(fack.GET).Method(fack.POST).Auth(true)
+	node.Function("/admin", index).Method(fack.GET).Method(fack.DELETE).Auth(true)
+
+	report := node.EffectiveAccess("reports")
+	if len(report.Routes) != 3 {
+		t.Fatalf("expected 3 routes in the report, got %d", len(report.Routes))
+	}
+
+	expected := map[string][4]string{
+		"/":       {"allow", "-", "-", "-"},
+		"/admin":  {"allow", "-", "-", "deny"},
+		"/export": {"allow", "allow", "-", "-"},
+	}
+
+	for _, route := range report.Routes {
+		for i, method := range route.Methods {
+			cell := "-"
+			if method.Reason != rpc.MethodNotSupported {
+				cell = "deny"
+				if method.Allowed {
+					cell = "allow"
+				}
+			}
+			if cell != expected[route.Path][i] {
+				t.Errorf("%s %s: expected %s, got %s (%s)", method.Method, route.Path, expected[route.Path][i], cell, method.Reason)
+			}
+		}
+	}
+
+	if !strings.Contains(report.Table(), "/export") {
+		t.Error("table does not contain every registered route")
+	}
+
+	data, err := report.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := rpc.AccessReport{}
+	if err := json.Unmarshal(data, &decoded); (err != nil) || (decoded.Endpoint != "reports") {
+		t.Error("report could not be round-tripped through JSON")
+	}
+}
+
+func TestNodeExplain(t *testing.T) {
+	auth := fack.NewAuth()
+	for _, name := range []string{"alice", "bob", "carol"} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		endpoint := fack.NewEndpoint(name, &key.PublicKey)
+		if name != "carol" {
+			endpoint.AddLocalPermission("/deploy", fack.NewPermission().Enable(fack.POST))
+		}
+		endpoint.AddLocalPermission("/users/{id}", fack.NewPermission().Enable(fack.GET))
+		auth.AddTrusted("10.0.0."+fmt.Sprint(len(name)), endpoint)
+	}
+
+	node := rpc.NewNode(fack.LocalHost().SetPort(ExplainPort), auth, fack.NewDevelopmentPolicy("10.0.0.9"))
+	node.Function("/status", index).Method(fack.GET)
+	node.Function("/users/{id}", echoPath).Method(fack.GET).Auth(true)
+	node.Function("/deploy", index).Method(fack.POST).MultiSig(2, "alice", "bob", "carol")
+	node.Function("/release", index).Method(fack.POST).MultiSig(3, "alice", "bob", "carol")
+	node.Explain("/explain").Auth(false)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(ExplainPort)
+	explain := func(endpoint, path, method string) map[string]any {
+		request := rpc.NewRequest("/explain")
+		request.Param = []string{endpoint, path, method}
+		resp, err := request.Send("POST", url)
+		if (err != nil) || (resp.GetStatus() != http.StatusOK) {
+			t.Fatalf("explain %s %s failed: %v %v", method, path, resp, err)
+		}
+		decision, _ := resp.GetData()["decision"].(map[string]any)
+		return decision
+	}
+
+	for _, c := range []struct {
+		endpoint, path, method string
+		authorized             bool
+		reason, resolved       string
+	}{
+		// permissions are looked up against the template the path resolves to
+		{"alice", "/users/42", "GET", true, "authorized", "/users/{id}"},
+		{"alice", "/users/42", "DELETE", false, "method_not_permitted", "/users/{id}"},
+		{"alice", "/status", "GET", true, "authorized", "/status"},
+		{"alice", "/missing", "GET", false, "route_not_found", "/missing"},
+		// enough signers are permitted to meet a threshold of two, not one of three
+		{"alice", "/deploy", "POST", true, "authorized", "/deploy"},
+		{"alice", "/release", "POST", false, "insufficient_signatures", "/release"},
+		{"10.0.0.9", "/users/42", "GET", true, "development_bypass", "/users/{id}"},
+	} {
+		decision := explain(c.endpoint, c.path, c.method)
+		if (decision["authorized"] != c.authorized) || (decision["reason"] != c.reason) || (decision["path"] != c.resolved) {
+			t.Errorf("%s %s for %s: %v", c.method, c.path, c.endpoint, decision)
+		}
+		if decision["dryRun"] != true {
+			t.Errorf("%s %s for %s was not reported as a dry run", c.method, c.path, c.endpoint)
+		}
+	}
+}
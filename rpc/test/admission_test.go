@@ -0,0 +1,409 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	AdmissionPort      = 8120
+	NodeAdmissionPort  = 8121
+	TimedAdmissionPort = 8125
+	PriorityPort       = 8126
+	AdmissionOrderPort = 8127
+	StarvationPort     = 8129
+)
+
+// call
+// Calls a Function with a plain HTTP GET so that the status and headers of a rejection can be read.
+func call(url string) (*http.Response, error) {
+	return callFrom(url, "", "{}")
+}
+
+// callFrom
+// Identical to call, the sender is given through X-Forwarded-For so that its priority class is
+// that of the endpoint trusted under the host.
+func callFrom(url, host, body string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(host) > 0 {
+		request.Header.Set("X-Forwarded-For", host)
+	}
+
+	resp, err := http.DefaultClient.Do(request)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func waitFor(condition func() bool) bool {
+	deadline := time.Now().Add(WaitForServerStart)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestRouteConcurrency(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(AdmissionPort))
+	node.RetryAfter(10 * time.Second)
+
+	release := make(chan struct{})
+	node.FunctionContext("/slow", func(ctx context.Context, request fack.Request, response fack.Response) {
+		<-release
+		response.SetStatus(http.StatusOK)
+	}).Method(fack.GET).Concurrency(1, 1)
+	node.Function("/", index).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(AdmissionPort)
+	metrics := node.Metrics()
+
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, err := call(url + "/slow")
+			if err != nil {
+				codes <- 0
+				return
+			}
+			codes <- resp.StatusCode
+		}()
+	}
+
+	if !waitFor(func() bool { return metrics.QueueDepth("/slow") == 1 }) {
+		t.Fatal("request beyond the limit was not queued")
+	}
+
+	resp, err := call(url + "/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (resp.StatusCode != http.StatusServiceUnavailable) || (resp.Header.Get("Retry-After") != "10") {
+		t.Errorf("request beyond the queue was not rejected with a 503 and Retry-After: %d", resp.StatusCode)
+	}
+	if metrics.Rejections("/slow") != 1 {
+		t.Error("rejected request was not counted")
+	}
+
+	if resp, err := call(url + "/"); (err != nil) || (resp.StatusCode != http.StatusOK) {
+		t.Error("limit of a route affected the other routes")
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("admitted request was answered with %d", code)
+		}
+	}
+	if metrics.QueueDepth("/slow") != 0 {
+		t.Error("queue was not drained")
+	}
+}
+
+func TestNodeConcurrency(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(NodeAdmissionPort))
+	node.Concurrency(1, 0)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	node.FunctionContext("/slow", func(ctx context.Context, request fack.Request, response fack.Response) {
+		close(started)
+		<-release
+		response.SetStatus(http.StatusOK)
+	}).Method(fack.GET)
+	node.Function("/", index).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(NodeAdmissionPort)
+
+	done := make(chan struct{})
+	go func() {
+		call(url + "/slow")
+		close(done)
+	}()
+	<-started
+
+	resp, err := call(url + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("node-wide limit did not reject a call to another route: %d", resp.StatusCode)
+	}
+	if node.Metrics().Rejections(rpc.NodeScope) != 1 {
+		t.Error("node-wide rejection was not counted")
+	}
+
+	close(release)
+	<-done
+
+	if resp, err := call(url + "/"); (err != nil) || (resp.StatusCode != http.StatusOK) {
+		t.Error("slot was not released once the handler finished")
+	}
+}
+
+func TestTimedRouteConcurrency(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(TimedAdmissionPort))
+
+	// the handler ignores its context, it keeps running once the client has received the 504
+	release := make(chan struct{})
+	finished := make(chan struct{}, 2)
+	node.FunctionContext("/export", func(ctx context.Context, request fack.Request, response fack.Response) {
+		<-release
+		response.SetStatus(http.StatusOK)
+		finished <- struct{}{}
+	}).Method(fack.GET).Timeout(50*time.Millisecond).Concurrency(1, 0)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(TimedAdmissionPort) + "/export"
+
+	resp, err := call(url)
+	if (err != nil) || (resp.StatusCode != http.StatusGatewayTimeout) {
+		t.Fatalf("handler past its deadline was not answered with a 504: %v", resp)
+	}
+
+	if resp, err = call(url); (err != nil) || (resp.StatusCode != http.StatusServiceUnavailable) {
+		t.Errorf("slot was released while the timed out handler was still running: %v", resp)
+	}
+
+	close(release)
+	<-finished
+
+	if !waitFor(func() bool {
+		resp, err := call(url)
+		return (err == nil) && (resp.StatusCode == http.StatusOK)
+	}) {
+		t.Error("slot was not released once the timed out handler returned")
+	}
+}
+
+// signedBody
+// A request to the function signed with the key, encoded as the body of a plain HTTP call.
+func signedBody(t *testing.T, function string, key *ecdsa.PrivateKey) string {
+	request := rpc.NewRequest(function)
+	if err := fack.Sign(request, key); err != nil {
+		t.Fatal(err)
+	}
+	return string(request.Bytes())
+}
+
+func TestPriorityDisplacement(t *testing.T) {
+	auth := fack.NewAuth()
+	keys := make(map[string]*ecdsa.PrivateKey)
+	for host, priority := range map[string]fack.Priority{
+		"10.0.0.1": fack.PriorityLow,
+		"10.0.0.2": fack.PriorityHigh,
+		"10.0.0.3": fack.PriorityNormal,
+	} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[host] = key
+
+		endpoint := fack.NewEndpoint(host, &key.PublicKey)
+		endpoint.AddGlobalPermission(fack.NewPermission().Enable(fack.GET))
+		endpoint.Priority = priority
+		auth.AddTrusted(host, endpoint)
+	}
+
+	node := rpc.NewNode(fack.LocalHost().SetPort(PriorityPort), auth)
+
+	release := make(chan struct{})
+	handler := func(ctx context.Context, request fack.Request, response fack.Response) {
+		<-release
+		response.SetStatus(http.StatusOK)
+	}
+	node.FunctionContext("/slow", handler).Method(fack.GET).Auth(true).Concurrency(1, 1)
+	node.FunctionContext("/open", handler).Method(fack.GET).Concurrency(1, 1)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(PriorityPort)
+	metrics := node.Metrics()
+
+	// the addresses carry a port as X-Forwarded-For is parsed as an address, the endpoint is
+	// only trusted with its priority once the signature of the request has been verified
+	send := func(path, host, body string) chan int {
+		code := make(chan int, 1)
+		go func() {
+			resp, err := callFrom(url+path, host, body)
+			if err != nil {
+				code <- 0
+				return
+			}
+			code <- resp.StatusCode
+		}()
+		return code
+	}
+	signed := func(host string) chan int {
+		return send("/slow", host+":4000", signedBody(t, "/slow", keys[host]))
+	}
+
+	// a normal request holds the slot and a low priority request waits in the queue
+	normal := signed("10.0.0.3")
+	if !waitFor(func() bool { return metrics.InFlight("/slow") == 1 }) {
+		t.Fatal("first request was not admitted")
+	}
+	low := signed("10.0.0.1")
+	if !waitFor(func() bool { return metrics.QueueDepth("/slow") == 1 }) {
+		t.Fatal("low priority request was not queued")
+	}
+
+	// the queue is full, a high priority request displaces the low priority one
+	high := signed("10.0.0.2")
+	select {
+	case code := <-low:
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("displaced request was answered with %d", code)
+		}
+	case <-time.After(WaitForServerStart):
+		t.Fatal("low priority request was not displaced")
+	}
+	if metrics.Rejections("/slow") != 1 {
+		t.Error("displaced request was not counted as a rejection")
+	}
+
+	// a normal request does not outrank the queued high priority request and is rejected
+	if code := <-signed("10.0.0.3"); code != http.StatusServiceUnavailable {
+		t.Errorf("a request was admitted into a full queue without outranking it: %d", code)
+	}
+
+	// an unsigned request claiming the address of the high priority endpoint stays normal
+	spoofed := send("/open", "", "{}")
+	if !waitFor(func() bool { return metrics.InFlight("/open") == 1 }) {
+		t.Fatal("request to the open route was not admitted")
+	}
+	queued := send("/open", "", "{}")
+	if !waitFor(func() bool { return metrics.QueueDepth("/open") == 1 }) {
+		t.Fatal("request to the open route was not queued")
+	}
+	if code := <-send("/open", "10.0.0.2:4000", "{}"); code != http.StatusServiceUnavailable {
+		t.Errorf("an address claimed through X-Forwarded-For displaced a queued request: %d", code)
+	}
+
+	close(release)
+	for name, code := range map[string]chan int{"admitted": normal, "high priority": high, "open": spoofed, "queued": queued} {
+		if c := <-code; c != http.StatusOK {
+			t.Errorf("%s request was answered with %d", name, c)
+		}
+	}
+}
+
+func TestRouteQueueDoesNotHoldNodeSlots(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(StarvationPort))
+	node.Concurrency(2, 0)
+
+	release := make(chan struct{})
+	node.FunctionContext("/export", func(ctx context.Context, request fack.Request, response fack.Response) {
+		<-release
+		response.SetStatus(http.StatusOK)
+	}).Method(fack.GET).Concurrency(1, 5)
+	node.Function("/", index).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(StarvationPort)
+	metrics := node.Metrics()
+
+	// one export runs and the rest wait on the queue of the route
+	codes := make(chan int, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			resp, err := call(url + "/export")
+			if err != nil {
+				codes <- 0
+				return
+			}
+			codes <- resp.StatusCode
+		}()
+	}
+	if !waitFor(func() bool { return metrics.QueueDepth("/export") == 3 }) {
+		t.Fatal("requests beyond the limit of the route were not queued")
+	}
+
+	if resp, err := call(url + "/"); (err != nil) || (resp.StatusCode != http.StatusOK) {
+		t.Errorf("a saturated route used up the limit of the node: %v", resp)
+	}
+	if metrics.Rejections(rpc.NodeScope) != 0 {
+		t.Error("requests queued on the route were rejected by the node")
+	}
+
+	close(release)
+	for i := 0; i < 4; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("queued request was answered with %d", code)
+		}
+	}
+}
+
+func TestAdmissionAfterAuthentication(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(AdmissionOrderPort))
+	node.Concurrency(1, 0)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	node.FunctionContext("/slow", func(ctx context.Context, request fack.Request, response fack.Response) {
+		close(started)
+		<-release
+		response.SetStatus(http.StatusOK)
+	}).Method(fack.GET)
+	node.Function("/secure", index).Method(fack.GET).Auth(true)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(AdmissionOrderPort)
+
+	done := make(chan struct{})
+	go func() {
+		call(url + "/slow")
+		close(done)
+	}()
+	<-started
+
+	// requests that would be rejected anyway are answered without waiting on a slot
+	if resp, err := call(url + "/secure"); (err != nil) || (resp.StatusCode != http.StatusUnauthorized) {
+		t.Errorf("unauthenticated request was not rejected before admission: %v", resp)
+	}
+	if resp, err := callFrom(url+"/slow", "", "{"); (err != nil) || (resp.StatusCode != http.StatusBadRequest) {
+		t.Errorf("malformed request was not rejected before admission: %v", resp)
+	}
+	if node.Metrics().Rejections(rpc.NodeScope) != 0 {
+		t.Error("requests rejected before admission were counted by the limiter")
+	}
+
+	close(release)
+	<-done
+}
@@ -0,0 +1,267 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const (
+	ArgumentsPort      = 8108
+	LegacyPort         = 8130
+	LegacyDisabledPort = 8131
+)
+
+type Filter struct {
+	Active bool `json:"active"`
+}
+
+type SearchInput struct {
+	Query  string `json:"query"`
+	Limit  int    `json:"limit"`
+	Filter Filter `json:"filter" param:",optional"`
+}
+
+func TestStructuredArguments(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(ArgumentsPort))
+
+	node.Function("/inspect", func(request fack.Request, response fack.Response) {
+		count, _ := request.Arg(0).Int()
+		ratio, _ := request.Arg(1).Float()
+		verbose, _ := request.Arg(2).Bool()
+		var filter Filter
+		request.Arg(3).Decode(&filter)
+
+		response.SetStatus(http.StatusOK).
+			Pair("count", count).
+			Pair("ratio", ratio).
+			Pair("verbose", verbose).
+			Pair("active", filter.Active).
+			Pair("args", request.ArgCount()).
+			Pair("missing", request.Arg(10).Exists())
+	}).Method(fack.GET)
+
+	rpc.Handle(node, "/search", func(ctx context.Context, in SearchInput) (SearchInput, error) {
+		return in, nil
+	}).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(ArgumentsPort)
+
+	resp, err := rpc.NewRequest("/inspect").AddArg(3).AddArg(0.5).AddArg(true).AddArg(Filter{Active: true}).Send("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := resp.GetData()
+	if (data["count"] != 3.0) || (data["ratio"] != 0.5) || (data["verbose"] != true) || (data["active"] != true) {
+		t.Errorf("typed accessors did not read the structured arguments: %v", data)
+	}
+	if (data["args"] != 4.0) || (data["missing"] != false) {
+		t.Errorf("argument count or existence was wrong: %v", data)
+	}
+
+	// a string-only client is read through the same accessors
+	legacy := rpc.NewRequest("/inspect")
+	legacy.Param = []string{"7", "1.25", "true"}
+	resp, err = legacy.Send("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data = resp.GetData(); (data["count"] != 7.0) || (data["ratio"] != 1.25) || (data["verbose"] != true) {
+		t.Errorf("typed accessors did not parse the legacy params: %v", data)
+	}
+
+	// params and args cannot be mixed, a policy reading params would see other values than the handler
+	mixed := rpc.NewRequest("/inspect").AddArg(3)
+	mixed.Param = []string{"9"}
+	resp, err = mixed.Send("GET", url)
+	if (err != nil) || (resp.GetStatus() != http.StatusBadRequest) || (resp.GetDescription() != rpc.MixedArguments) {
+		t.Errorf("a request carrying both params and args was not rejected: %v", resp)
+	}
+
+	resp, err = rpc.NewRequest("/search").SetArg("query", "fack").SetArg("limit", 5).
+		SetArg("filter", map[string]any{"active": true}).Send("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = resp.GetData()
+	if (resp.GetStatus() != http.StatusOK) || (data["query"] != "fack") || (data["limit"] != 5.0) {
+		t.Errorf("named arguments were not decoded into the typed input: %v", resp)
+	}
+	if filter, ok := data["filter"].(map[string]any); !ok || (filter["active"] != true) {
+		t.Errorf("nested argument was not decoded into the typed input: %v", data)
+	}
+
+	resp, err = rpc.NewRequest("/search").SetArg("query", "fack").Send("GET", url)
+	if (err != nil) || (resp.GetStatus() != http.StatusBadRequest) || (resp.GetDescription() != rpc.SyntaxMismatch) {
+		t.Error("missing named argument did not return a syntax mismatch")
+	}
+}
+
+func TestArgumentsWireFormat(t *testing.T) {
+	request := rpc.NewRequest("/users").SetArg("id", 42)
+	if !bytes.Contains(request.Bytes(), []byte(`"args":{"id":42}`)) {
+		t.Errorf("named arguments were not encoded as a JSON object: %s", request.Bytes())
+	}
+
+	decoded := rpc.NewRequest("")
+	if err := json.Unmarshal([]byte(`{"function":"/users","args":[1,"two",{"three":3}]}`), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := decoded.Arg(1).String(); (decoded.ArgCount() != 3) || (s != "two") {
+		t.Error("positional arguments were not decoded from a JSON array")
+	}
+	if params := decoded.GetParams(); (len(params) != 3) || (params[0] != "1") || (params[2] != `{"three":3}`) {
+		t.Errorf("positional arguments were not exposed as params: %v", params)
+	}
+
+	if err := json.Unmarshal([]byte(`{"function":"/users","args":"id"}`), decoded); err == nil {
+		t.Error("arguments that are neither an array nor an object were accepted")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("mixing positional and named arguments did not panic")
+		}
+	}()
+	rpc.NewRequest("/users").AddArg(1).SetArg("id", 1)
+}
+
+func TestSignatureCoversArguments(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("could not generate an ECDSA key pair")
+	}
+	endpoint := fack.NewEndpoint("alice", &key.PublicKey)
+
+	request := rpc.NewRequest("/transfer").SetArg("amount", 10).SetArg("to", "bob")
+	if err := fack.Sign(request, key); err != nil {
+		t.Fatal(err)
+	}
+	if !endpoint.ValidateSource(request) {
+		t.Fatal("signed request with arguments was not verified")
+	}
+
+	// the canonical encoding hashes equivalent numbers and key orders the same
+	var equivalent rpc.Request
+	body := fmt.Sprintf(`{"function":"/transfer","args":{"to":"bob","amount":10.0},"auth":{"nonce":%d}}`, request.GetNonce())
+	if err := json.Unmarshal([]byte(body), &equivalent); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(equivalent.GetHash(), request.GetHash()) {
+		t.Error("equivalent arguments did not produce the same hash")
+	}
+
+	request.SetArg("amount", 10000)
+	if endpoint.ValidateSource(request) {
+		t.Error("a request with altered arguments passed signature verification")
+	}
+
+	legacy := rpc.NewRequest("/transfer")
+	legacy.Param = []string{"10", "bob"}
+	if err := fack.Sign(legacy, key); err != nil {
+		t.Fatal(err)
+	}
+	if !endpoint.ValidateSource(legacy) {
+		t.Fatal("signed request with params was not verified")
+	}
+
+	legacy.Param[0] = "10000"
+	if endpoint.ValidateSource(legacy) {
+		t.Error("a request with altered params passed signature verification")
+	}
+
+	// params and positional args with the same values must not produce the same hash
+	positional := rpc.NewRequest("/transfer").AddArg("10").AddArg("bob")
+	positional.SetNonce(legacy.GetNonce())
+	legacy.Param[0] = "10"
+	if bytes.Equal(positional.GetHash(), legacy.GetHash()) {
+		t.Error("params and args with the same values produced the same hash")
+	}
+}
+
+// signBaseline
+// Signs the request the way clients that predate structured arguments do, over the function and
+// the nonce alone.
+func signBaseline(t *testing.T, request *rpc.Request, key *ecdsa.PrivateKey) {
+	request.SetNonce(fack.GenerateNonce())
+	hash := sha256.Sum256([]byte(request.Function + strconv.FormatInt(request.GetNonce(), 10)))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.SetSignature(signature)
+}
+
+func TestLegacyParamSignatures(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("could not generate an ECDSA key pair")
+	}
+
+	start := func(port int, legacy bool) string {
+		endpoint := fack.NewEndpoint("legacy", &key.PublicKey)
+		endpoint.AddGlobalPermission(fack.NewPermission().Enable(fack.POST))
+		auth := fack.NewAuth()
+		auth.AddTrusted("127.0.0.1", endpoint)
+
+		node := rpc.NewNode(fack.LocalHost().SetPort(port), auth)
+		node.LegacyParamSignatures(legacy)
+		node.Function("/transfer", index).Method(fack.POST).Auth(true)
+
+		go node.Start()
+		t.Cleanup(func() { node.Shutdown() })
+		return LocalHost + fmt.Sprint(port)
+	}
+	url, disabled := start(LegacyPort, true), start(LegacyDisabledPort, false)
+	time.Sleep(WaitForServerStart)
+
+	send := func(request *rpc.Request, url string) int {
+		resp, err := request.Send("POST", url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.GetStatus()
+	}
+
+	baseline := rpc.NewRequest("/transfer")
+	baseline.Param = []string{"10", "bob"}
+	signBaseline(t, baseline, key)
+	if status := send(baseline, url); status != http.StatusOK {
+		t.Errorf("request signed with the baseline hash was rejected: %d", status)
+	}
+	signBaseline(t, baseline, key)
+	if status := send(baseline, disabled); status != http.StatusUnauthorized {
+		t.Errorf("baseline hash was accepted without the compatibility option: %d", status)
+	}
+
+	current := rpc.NewRequest("/transfer")
+	current.Param = []string{"10", "bob"}
+	if err := fack.Sign(current, key); err != nil {
+		t.Fatal(err)
+	}
+	if status := send(current, url); status != http.StatusOK {
+		t.Errorf("request signed with the current hash was rejected: %d", status)
+	}
+
+	// the baseline hash never covers args, a request holding them must sign the current hash
+	arguments := rpc.NewRequest("/transfer").AddArg(10).AddArg("bob")
+	signBaseline(t, arguments, key)
+	if status := send(arguments, url); status != http.StatusUnauthorized {
+		t.Errorf("request with args was accepted with the baseline hash: %d", status)
+	}
+}
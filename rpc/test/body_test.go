@@ -0,0 +1,65 @@
+package test
+
+import (
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	BodyPort = 8122
+)
+
+func post(url, content, body string) (int, error) {
+	resp, err := http.Post(url, content, strings.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestBodyLimits(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(BodyPort))
+	node.BodyLimit(64)
+	node.DisallowUnknownFields(true)
+	node.Function("/small", index).Method(fack.POST)
+	node.Function("/large", index).Method(fack.POST).BodyLimit(4096)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(BodyPort)
+	large := `{"function":"/small","param":["` + strings.Repeat("a", 1024) + `"]}`
+
+	cases := []struct {
+		name    string
+		path    string
+		content string
+		body    string
+		status  int
+	}{
+		{"body within the node limit", "/small", "application/json", `{"function":"/small"}`, http.StatusOK},
+		{"body over the node limit", "/small", "application/json", large, http.StatusRequestEntityTooLarge},
+		{"body within the route limit", "/large", "application/json", large, http.StatusOK},
+		{"media type with parameters", "/small", "application/json; charset=utf-8", `{"function":"/small"}`, http.StatusOK},
+		{"media type that is not JSON", "/small", "text/plain", `{"function":"/small"}`, http.StatusUnsupportedMediaType},
+		{"unknown field", "/small", "application/json", `{"function":"/small","extra":1}`, http.StatusBadRequest},
+		{"trailing data", "/small", "application/json", `{"function":"/small"} {}`, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		status, err := post(url+c.path, c.content, c.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != c.status {
+			t.Errorf("%s: expected %d, received %d", c.name, c.status, status)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	CodecPort       = 8123
+	CustomCodecPort = 8128
+)
+
+var codecs = []rpc.StrictCodec{rpc.JSON, rpc.MessagePack, rpc.CBOR}
+
+func TestCodecRoundTrip(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("could not generate an ECDSA key pair")
+	}
+	endpoint := fack.NewEndpoint("alice", &key.PublicKey)
+
+	request := rpc.NewRequest("/transfer").AddArg(10).AddArg(1.5).AddArg("bob").
+		AddArg(map[string]any{"memo": "rent", "tags": []string{"home"}}).AddArg(nil).AddArg(true)
+	if err := fack.Sign(request, key); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, codec := range codecs {
+		data, err := codec.Marshal(request)
+		if err != nil {
+			t.Fatalf("%s: %s", codec.ContentType(), err)
+		}
+
+		decoded := new(rpc.Request)
+		if err := codec.Unmarshal(data, decoded); err != nil {
+			t.Fatalf("%s: %s", codec.ContentType(), err)
+		}
+
+		if (decoded.Function != "/transfer") || !bytes.Equal(decoded.GetSignature(), request.GetSignature()) {
+			t.Errorf("%s: request was not decoded as it was encoded", codec.ContentType())
+		}
+		if !bytes.Equal(decoded.GetHash(), request.GetHash()) || !endpoint.ValidateSource(decoded) {
+			t.Errorf("%s: signature did not verify after the request was decoded", codec.ContentType())
+		}
+
+		amount, _ := decoded.Arg(0).Int()
+		ratio, _ := decoded.Arg(1).Float()
+		var memo struct {
+			Tags []string `json:"tags"`
+		}
+		decoded.Arg(3).Decode(&memo)
+		if (amount != 10) || (ratio != 1.5) || (len(memo.Tags) != 1) || (decoded.ArgCount() != 6) {
+			t.Errorf("%s: arguments were not read the same as JSON arguments", codec.ContentType())
+		}
+	}
+}
+
+func TestCodecEncodings(t *testing.T) {
+	value := map[string]any{"a": 1, "b": []any{true, nil}}
+
+	msgpack, _ := rpc.MessagePack.Marshal(value)
+	if !bytes.Equal(msgpack, []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x92, 0xc3, 0xc0}) {
+		t.Errorf("unexpected MessagePack encoding % x", msgpack)
+	}
+
+	cbor, _ := rpc.CBOR.Marshal(value)
+	if !bytes.Equal(cbor, []byte{0xa2, 0x61, 'a', 0x01, 0x61, 'b', 0x82, 0xf5, 0xf6}) {
+		t.Errorf("unexpected CBOR encoding % x", cbor)
+	}
+
+	// an indefinite length array holding a half precision float, a chunked string and -100
+	var items []any
+	err := rpc.CBOR.Unmarshal([]byte{0x9f, 0xf9, 0x3c, 0x00, 0x7f, 0x61, 'h', 0x61, 'i', 0xff, 0x38, 0x63, 0xff}, &items)
+	if (err != nil) || !reflect.DeepEqual(items, []any{1.0, "hi", int64(-100)}) {
+		t.Errorf("CBOR was not decoded: %v %v", items, err)
+	}
+
+	// a declared length that the body cannot hold is rejected before anything is allocated
+	if err := rpc.MessagePack.Unmarshal([]byte{0xdd, 0xff, 0xff, 0xff, 0xff}, &items); err == nil {
+		t.Error("truncated MessagePack array was decoded")
+	}
+	if err := rpc.CBOR.Unmarshal(append(cbor, 0x00), &value); err == nil {
+		t.Error("trailing data after the CBOR value was ignored")
+	}
+
+	unknown, _ := rpc.MessagePack.Marshal(map[string]any{"function": "/users", "extra": 1})
+	if err := rpc.MessagePack.Unmarshal(unknown, new(rpc.Request)); err != nil {
+		t.Error("unknown field was rejected by a lenient decode")
+	}
+	if err := rpc.MessagePack.UnmarshalStrict(unknown, new(rpc.Request)); err == nil {
+		t.Error("unknown field was accepted by a strict decode")
+	}
+}
+
+func TestCodecNegotiation(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("could not generate an ECDSA key pair")
+	}
+
+	auth := fack.NewAuth()
+	endpoint := fack.NewEndpoint("alice", &key.PublicKey)
+	endpoint.AddGlobalPermission(fack.NewPermission().Enable(fack.POST))
+	auth.AddTrusted("127.0.0.1", endpoint)
+
+	node := rpc.NewNode(fack.LocalHost().SetPort(CodecPort), auth)
+	node.Function("/", index).Method(fack.POST)
+	node.Function("/transfer", func(request fack.Request, response fack.Response) {
+		amount, _ := request.Arg(0).Float()
+		to, _ := request.Arg(1).String()
+		response.SetStatus(http.StatusOK).Pair("amount", amount).Pair("to", to)
+	}).Method(fack.POST).Auth(true)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(CodecPort)
+
+	for i, codec := range codecs {
+		request := rpc.NewRequest("/transfer").AddArg(12.5).AddArg("bob").Codec(codec)
+		request.SetNonce(int64(i+1) * 10)
+		if err := fack.Sign(request, key); err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := request.Send("POST", url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (resp.GetStatus() != http.StatusOK) || (resp.GetData()["amount"] != 12.5) || (resp.GetData()["to"] != "bob") {
+			t.Errorf("%s: signed request was not answered: %d %s", codec.ContentType(), resp.GetStatus(), resp.GetDescription())
+		}
+	}
+
+	post := func(content, accept string) *http.Response {
+		r, _ := http.NewRequest(http.MethodPost, url+"/", strings.NewReader(`{"function":"/"}`))
+		r.Header.Set("Content-Type", content)
+		r.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("application/json", "application/cbor;q=0.5, application/msgpack"); resp.Header.Get("Content-Type") != rpc.MessagePackContentType {
+		t.Errorf("response was not sent with the preferred codec: %s", resp.Header.Get("Content-Type"))
+	}
+	if resp := post("application/json", "*/*"); resp.Header.Get("Content-Type") != rpc.JSONContentType {
+		t.Error("response was not sent with the codec of the request")
+	}
+	if resp := post("application/xml", ""); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Error("request without a codec was not rejected")
+	}
+}
+
+// taggedCodec
+// JSON behind a prefix, a codec neither the node nor the client knows unless it is registered.
+type taggedCodec struct{}
+
+func (taggedCodec) ContentType() string {
+	return "application/vnd.tagged"
+}
+
+func (taggedCodec) Marshal(v any) ([]byte, error) {
+	data, err := rpc.JSON.Marshal(v)
+	return append([]byte("tagged:"), data...), err
+}
+
+func (taggedCodec) Unmarshal(data []byte, v any) error {
+	if !bytes.HasPrefix(data, []byte("tagged:")) {
+		return errors.New("missing tag")
+	}
+	return rpc.JSON.Unmarshal(bytes.TrimPrefix(data, []byte("tagged:")), v)
+}
+
+func TestCustomCodec(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(CustomCodecPort))
+	node.Codec(taggedCodec{})
+	node.Function("/echo", func(request fack.Request, response fack.Response) {
+		name, _ := request.Arg(0).String()
+		response.SetStatus(http.StatusOK).Pair("name", name)
+	}).Method(fack.POST)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(CustomCodecPort)
+
+	// the response is decoded with the codec the request was sent with
+	resp, err := rpc.NewRequest("/echo").AddArg("ada").Codec(taggedCodec{}).Send("POST", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (resp.GetStatus() != http.StatusOK) || (resp.GetData()["name"] != "ada") {
+		t.Errorf("response of a custom codec was not decoded: %v", resp)
+	}
+
+	// the node answers with the codec of the request when the client sends no Accept header
+	r, _ := http.NewRequest(http.MethodPost, url+"/echo", strings.NewReader(`tagged:{"function":"/echo"}`))
+	r.Header.Set("Content-Type", "application/vnd.tagged")
+	raw, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.Body.Close()
+	if raw.Header.Get("Content-Type") != "application/vnd.tagged" {
+		t.Fatalf("node did not answer with the custom codec: %s", raw.Header.Get("Content-Type"))
+	}
+
+	// a response that cannot be decoded (the plain text 404 of the router) is an error, not an empty Response
+	if _, err := rpc.NewRequest("/echo").AddArg("ada").Codec(taggedCodec{}).Send("POST", url+"/missing"); err == nil {
+		t.Error("a response that could not be decoded did not return an error")
+	}
+}
+
+func TestStrictNodeRequiresStrictCodecs(t *testing.T) {
+	expectPanic := func(name string, configure func(node *rpc.Node)) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: a codec without strict decoding was accepted by a strict node", name)
+			}
+		}()
+		configure(rpc.NewNode(fack.LocalHost().SetPort(CustomCodecPort)))
+	}
+
+	expectPanic("codec after", func(node *rpc.Node) {
+		node.DisallowUnknownFields(true)
+		node.Codec(taggedCodec{})
+	})
+	expectPanic("codec before", func(node *rpc.Node) {
+		node.Codec(taggedCodec{})
+		node.DisallowUnknownFields(true)
+	})
+
+	node := rpc.NewNode(fack.LocalHost().SetPort(CustomCodecPort))
+	node.DisallowUnknownFields(true)
+	node.Codec(rpc.CBOR)
+}
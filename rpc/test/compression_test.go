@@ -0,0 +1,137 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	CompressionPort = 8124
+)
+
+// base64Compressor
+// Stands in for a compressor registered by the user (ex. zstd), it only needs to round-trip.
+type base64Compressor struct{}
+
+func (base64Compressor) Encoding() string {
+	return "x-base64"
+}
+
+func (base64Compressor) Compress(data []byte) ([]byte, error) {
+	return []byte(base64.StdEncoding.EncodeToString(data)), nil
+}
+
+func (base64Compressor) Decompress(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(base64.NewDecoder(base64.StdEncoding, r)), nil
+}
+
+func TestCompression(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(CompressionPort))
+	node.CompressionThreshold(256)
+	node.BodyLimit(64 << 10)
+	node.Compressor(base64Compressor{})
+	node.Function("/", index).Method(fack.POST)
+	node.Function("/export", func(request fack.Request, response fack.Response) {
+		items := make([]string, 100)
+		for i := range items {
+			items[i] = fmt.Sprintf("item-%d", i)
+		}
+		response.SetStatus(http.StatusOK).Pair("items", items).Pair("count", request.ArgCount())
+	}).Method(fack.POST)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(CompressionPort)
+
+	// the transport would otherwise ask for gzip itself and hide the Content-Encoding
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	post := func(path, encoding string, body []byte, headers map[string]string) (*http.Response, []byte) {
+		r, _ := http.NewRequest(http.MethodPost, url+path, bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept-Encoding", encoding)
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+		resp, err := client.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, data
+	}
+
+	resp, data := post("/export", "gzip", []byte(`{"function":"/export"}`), nil)
+	if (resp.Header.Get("Content-Encoding") != "gzip") || !strings.Contains(resp.Header.Get("Vary"), "Accept-Encoding") {
+		t.Fatalf("response over the threshold was not compressed: %v", resp.Header)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded rpc.Response
+	if err := json.NewDecoder(reader).Decode(&decoded); (err != nil) || (len(decoded.GetData()["items"].([]any)) != 100) {
+		t.Error("compressed response could not be decoded")
+	}
+
+	if resp, _ := post("/", "gzip", []byte(`{"function":"/"}`), nil); resp.Header.Get("Content-Encoding") != "" {
+		t.Error("response under the threshold was compressed")
+	}
+	if resp, _ := post("/export", "", []byte(`{"function":"/export"}`), nil); !strings.Contains(resp.Header.Get("Vary"), "Accept-Encoding") {
+		t.Error("response of a client that accepts no encoding did not vary on Accept-Encoding")
+	}
+	if resp, _ := post("/export", "gzip;q=0", []byte(`{"function":"/export"}`), nil); resp.Header.Get("Content-Encoding") != "" {
+		t.Error("encoding refused by the client was used")
+	}
+	if resp, _ := post("/export", "gzip;q=0.5, x-base64", []byte(`{"function":"/export"}`), nil); resp.Header.Get("Content-Encoding") != "x-base64" {
+		t.Error("registered compressor preferred by the client was not used")
+	}
+
+	compressed := new(bytes.Buffer)
+	writer := gzip.NewWriter(compressed)
+	writer.Write([]byte(`{"function":"/export","args":[1,2,3]}`))
+	writer.Close()
+	resp, data = post("/export", "", compressed.Bytes(), map[string]string{"Content-Encoding": "gzip"})
+	if (resp.StatusCode != http.StatusOK) || !strings.Contains(string(data), `"count":3`) {
+		t.Errorf("compressed request body was not decompressed: %d %s", resp.StatusCode, data)
+	}
+
+	// a body that is small on the wire is still rejected once its decompressed length is over the limit
+	bomb := new(bytes.Buffer)
+	writer = gzip.NewWriter(bomb)
+	writer.Write([]byte(`{"function":"/export","args":["`))
+	writer.Write(bytes.Repeat([]byte("a"), 1<<20))
+	writer.Write([]byte(`"]}`))
+	writer.Close()
+	if bomb.Len() >= 64<<10 {
+		t.Fatalf("compressed body is not under the limit: %d bytes", bomb.Len())
+	}
+	if resp, _ := post("/export", "", bomb.Bytes(), map[string]string{"Content-Encoding": "gzip"}); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("decompressed body over the limit was not rejected with a 413: %d", resp.StatusCode)
+	}
+	if resp, _ := post("/export", "", []byte(`{"function":"/export"}`), map[string]string{"Content-Encoding": "br"}); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Error("request body with an unsupported encoding was not rejected")
+	}
+
+	// the client advertises gzip and decodes the response transparently
+	sent, err := rpc.NewRequest("/export").Send("POST", url)
+	if (err != nil) || (sent.GetStatus() != http.StatusOK) || (len(sent.GetData()["items"].([]any)) != 100) {
+		t.Errorf("client did not decode the compressed response: %v", err)
+	}
+	sent, err = rpc.NewRequest("/export").AcceptEncoding(base64Compressor{}).Send("POST", url)
+	if (err != nil) || (sent.GetStatus() != http.StatusOK) || (len(sent.GetData()["items"].([]any)) != 100) {
+		t.Errorf("client did not decode the response of a registered compressor: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const (
+	ContextPort  = 8105
+	CancelPort   = 8106
+	ShortTimeout = 100 * time.Millisecond
+)
+
+func TestFunctionDeadline(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(ContextPort))
+
+	cancelled := make(chan error, 1)
+	node.FunctionContext("/deadline", func(ctx context.Context, request fack.Request, response fack.Response) {
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		response.SetStatus(http.StatusOK)
+	}).Method(fack.GET).Timeout(ShortTimeout)
+
+	node.FunctionContext("/fast", func(ctx context.Context, request fack.Request, response fack.Response) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("handler context does not carry the route deadline")
+		}
+		response.SetStatus(http.StatusOK).SetDescription(SuccessMessage).Pair("fast", true)
+	}).Method(fack.GET).Timeout(time.Second)
+
+	// a Router registered through Function still works alongside context-aware handlers
+	node.Function("/legacy", index).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(ContextPort)
+
+	resp, err := rpc.NewRequest("/deadline").Send("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != http.StatusGatewayTimeout {
+		t.Errorf("exceeded deadline returned %d instead of 504", resp.GetStatus())
+	}
+	if err := <-cancelled; !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("handler context was not cancelled by the deadline")
+	}
+
+	resp, err = rpc.NewRequest("/fast").Send("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (resp.GetStatus() != http.StatusOK) || (resp.GetData()["fast"] != true) {
+		t.Error("handler response within the deadline was not returned")
+	}
+
+	resp, err = rpc.NewRequest("/legacy").Send("GET", url)
+	if (err != nil) || (resp.GetStatus() != http.StatusOK) {
+		t.Error("router adapter did not call the legacy handler")
+	}
+}
+
+func TestShutdownCancelsHandlerContext(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(CancelPort))
+	node.DrainTimeout(ShortTimeout)
+
+	cancelled := make(chan error, 1)
+	node.FunctionContext("/block", func(ctx context.Context, request fack.Request, response fack.Response) {
+		select {
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+		case <-time.After(5 * time.Second):
+			cancelled <- nil
+		}
+	}).Method(fack.GET)
+
+	go node.Start()
+	time.Sleep(WaitForServerStart)
+
+	go rpc.NewRequest("/block").Send("GET", LocalHost+fmt.Sprint(CancelPort))
+	time.Sleep(ShortTimeout)
+
+	node.Shutdown()
+
+	select {
+	case err := <-cancelled:
+		if err == nil {
+			t.Error("handler context was not cancelled by the shutdown")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("handler did not observe the shutdown")
+	}
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const (
+	DescribePort = 8109
+)
+
+func TestDescribeEndpoint(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(DescribePort))
+	node.Function("/users", index).Method(fack.GET).Method(fack.POST).Describe("Looks up a user")
+	node.Function("/deploy", index).Method(fack.POST).MultiSig(2, "alice", "bob").Timeout(time.Second)
+	node.Function("/admin", index).Method(fack.GET).Auth(true)
+	node.Function("/public", index).Method(fack.GET).Auth(true).Auth(false)
+	node.Describe("/describe")
+	node.Describe("/private").Auth(true)
+
+	description := node.Description()
+	if len(description.Routes) != 6 {
+		t.Fatalf("expected 6 routes in the description, got %d", len(description.Routes))
+	}
+
+	routes := make(map[string]rpc.RouteDescription)
+	for _, route := range description.Routes {
+		routes[route.Path] = route
+	}
+
+	if users := routes["/users"]; (len(users.Methods) != 2) || (users.Description != "Looks up a user") || users.RequiresAuth {
+		t.Errorf("route was not described correctly: %+v", users)
+	}
+	if deploy := routes["/deploy"]; (deploy.Threshold != 2) || (len(deploy.Signers) != 2) || (deploy.Timeout != "1s") {
+		t.Errorf("multi-signature route was not described correctly: %+v", deploy)
+	}
+	if !routes["/admin"].RequiresAuth || routes["/public"].RequiresAuth {
+		t.Error("auth requirement was not described correctly")
+	}
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(DescribePort)
+
+	resp, err := rpc.NewRequest("/describe").Send("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed, ok := resp.GetData()["routes"].([]any)
+	if (resp.GetStatus() != http.StatusOK) || !ok || (len(listed) != 6) {
+		t.Errorf("describe endpoint did not list every route: %v", resp)
+	}
+
+	resp, err = rpc.NewRequest("/private").Send("GET", url)
+	if (err != nil) || (resp.GetStatus() != http.StatusUnauthorized) {
+		t.Error("authenticated describe endpoint answered an unsigned request")
+	}
+}
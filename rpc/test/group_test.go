@@ -0,0 +1,81 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const (
+	GroupPort = 8112
+)
+
+func tag(name string) fack.Middleware {
+	return func(next fack.Router) fack.Router {
+		return func(request fack.Request, response fack.Response) {
+			next(request, response)
+			trail, _ := response.GetData()["trail"].(string)
+			response.GetData()["trail"] = name + trail
+		}
+	}
+}
+
+func TestRouteGroups(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(GroupPort))
+
+	admin := node.Group("/admin").Method(fack.GET).Auth(true).Debug(true).Use(tag("admin"))
+	users := admin.Group("/users").Use(tag(">users"))
+	public := admin.Group("/public").Auth(false).Method(fack.POST)
+
+	stats := admin.Function("/stats", index)
+	list := users.Function("", index)
+	lookup := users.Function("/{id}", echoPath).Use(tag(">route"))
+	health := public.Function("/health", index)
+	typed := rpc.Handle(public, "/sum", sum)
+
+	// settings changed on the parent after a nested group was created are still inherited
+	admin.Method(fack.DELETE)
+	late := users.Function("/late", index)
+
+	if (stats.GetPath() != "/admin/stats") || (list.GetPath() != "/admin/users") || (lookup.GetPath() != "/admin/users/{id}") || (typed.GetPath() != "/admin/public/sum") {
+		t.Error("group prefixes were not joined to the function paths")
+	}
+	if !stats.RequiresAuth || !lookup.RequiresAuth || !stats.Debug || !lookup.Debug {
+		t.Error("auth or debug was not inherited from the group")
+	}
+	if health.RequiresAuth || !health.Debug {
+		t.Error("nested group could not override the auth requirement of its parent")
+	}
+	if !lookup.IsMethodSupported(fack.GET) || health.IsMethodSupported(fack.GET) || !health.IsMethodSupported(fack.POST) {
+		t.Error("default methods were not inherited (or overridden) by the routes")
+	}
+	if !late.IsMethodSupported(fack.DELETE) || lookup.IsMethodSupported(fack.DELETE) {
+		t.Error("methods were not resolved when the route was registered")
+	}
+
+	// the nested routes are served without auth once the group is left public
+	node.Group("/open").Method(fack.GET).Use(tag("open")).Group("/v1").Use(tag(">v1")).
+		FunctionContext("/items/{id}", func(ctx context.Context, request fack.Request, response fack.Response) {
+			echoPath(request, response)
+		}).Use(tag(">route"))
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(GroupPort)
+
+	resp, err := rpc.NewRequest("/open/v1/items/9").Send("GET", url)
+	if (err != nil) || (resp.GetData()["id"] != "9") || (resp.GetData()["trail"] != "open>v1>route") {
+		t.Errorf("group middleware did not run in order around the route: %v", resp)
+	}
+
+	resp, err = rpc.NewRequest("/admin/stats").Send("GET", url)
+	if (err != nil) || (resp.GetStatus() != http.StatusUnauthorized) {
+		t.Error("route in an authenticated group answered an unsigned request")
+	}
+}
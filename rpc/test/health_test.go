@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	HealthPort = 8114
+)
+
+func probe(t *testing.T, url string) (int, rpc.Health, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var health rpc.Health
+	json.NewDecoder(resp.Body).Decode(&health)
+	return resp.StatusCode, health, resp.Header.Get("Retry-After")
+}
+
+func TestHealthAndFrozenNode(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(HealthPort))
+	node.Function("/", index).Method(fack.GET)
+	node.RetryAfter(30 * time.Second)
+	node.ServeHealth()
+
+	healthy := int32(1)
+	node.HealthCheck("database", func(ctx context.Context) error {
+		if atomic.LoadInt32(&healthy) == 0 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	url := LocalHost + fmt.Sprint(HealthPort)
+
+	if err := node.Freeze(); err == nil {
+		t.Error("a node that is not running was frozen")
+	}
+
+	go node.Start()
+	time.Sleep(WaitForServerStart)
+
+	if code, health, _ := probe(t, url+rpc.ReadinessPath); (code != http.StatusOK) || !health.Ready || (health.Checks["database"] != rpc.HealthCheckPassed) {
+		t.Errorf("running node with passing checks was not ready: %d %+v", code, health)
+	}
+
+	atomic.StoreInt32(&healthy, 0)
+	if code, health, _ := probe(t, url+rpc.ReadinessPath); (code != http.StatusServiceUnavailable) || (health.Checks["database"] != "connection refused") {
+		t.Errorf("failing health check did not flip readiness: %d %+v", code, health)
+	}
+	if code, _, _ := probe(t, url+rpc.LivenessPath); code != http.StatusOK {
+		t.Error("failing health check affected liveness")
+	}
+	atomic.StoreInt32(&healthy, 1)
+
+	if err := node.Freeze(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(url + "/")
+	if (err != nil) || (resp.StatusCode != http.StatusServiceUnavailable) || (resp.Header.Get("Retry-After") != "30") {
+		t.Error("frozen node did not answer function calls with a 503 and Retry-After")
+	}
+	if code, health, retry := probe(t, url+rpc.ReadinessPath); (code != http.StatusServiceUnavailable) || (health.Status != "Frozen") || (retry != "30") {
+		t.Errorf("frozen node was reported as ready: %d %+v", code, health)
+	}
+	if code, _, _ := probe(t, url+rpc.LivenessPath); code != http.StatusOK {
+		t.Error("frozen node was reported as not alive")
+	}
+
+	if err := node.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := rpc.NewRequest("/").Send("GET", url); (err != nil) || (resp.GetStatus() != http.StatusOK) {
+		t.Error("resumed node did not call its functions")
+	}
+
+	node.Shutdown()
+	if node.GetStatus() != rpc.Killed {
+		t.Error("node was not killed by the shutdown")
+	}
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	LoggerPort = 8116
+)
+
+// syncBuffer
+// The node writes entries from its own goroutines while the test reads them.
+type syncBuffer struct {
+	buffer bytes.Buffer
+	mutex  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func TestNodeLogger(t *testing.T) {
+	output := new(syncBuffer)
+	node := rpc.NewNode(fack.LocalHost().SetPort(LoggerPort), "logged", fack.NewTextLogger(output, fack.LevelInfo))
+	node.Function("/quiet", index).Method(fack.GET)
+	node.Function("/loud", index).Method(fack.GET).Debug = true
+
+	go node.Start()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(LoggerPort)
+	rpc.NewRequest("/quiet").Send("GET", url)
+	rpc.NewRequest("/loud").Send("GET", url)
+	rpc.NewRequest("/loud").Send("DELETE", url)
+	node.Shutdown()
+
+	logs := output.String()
+	if !strings.Contains(logs, "http node started node=logged") || !strings.Contains(logs, "http node shutdown node=logged") {
+		t.Errorf("node lifecycle was not logged: %s", logs)
+	}
+	if strings.Contains(logs, "path=/quiet") {
+		t.Error("entries of a route without Debug were written at the info level")
+	}
+	if !strings.Contains(logs, "function called node=logged path=/loud method=GET") || !strings.Contains(logs, "status=200") {
+		t.Errorf("call to a Debug route was not logged with its fields: %s", logs)
+	}
+	if !strings.Contains(logs, "method not supported by the path node=logged path=/loud method=DELETE") {
+		t.Errorf("rejected method was not logged: %s", logs)
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	MetricsPort = 8115
+)
+
+func TestMetrics(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(MetricsPort))
+	node.Function("/users/{id}", echoPath).Method(fack.GET)
+	node.Function("/admin", index).Method(fack.GET).Auth(true)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	node.FunctionContext("/slow", func(ctx context.Context, request fack.Request, response fack.Response) {
+		close(started)
+		<-release
+		response.SetStatus(http.StatusOK)
+	}).Method(fack.GET)
+	node.ServeMetrics()
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(MetricsPort)
+
+	rpc.NewRequest("/users/1").Send("GET", url)
+	rpc.NewRequest("/users/2").Send("GET", url)
+	rpc.NewRequest("/users/3").Send("POST", url)
+	rpc.NewRequest("/admin").Send("GET", url)
+	rpc.NewRequest("/users/4").Send("BREW", url)
+	rpc.NewRequest("/users/5").Send("brew", url)
+
+	go rpc.NewRequest("/slow").Send("GET", url)
+	<-started
+
+	metrics := node.Metrics()
+	if metrics.Requests("/users/{id}", "GET", http.StatusOK) != 2 {
+		t.Error("requests were not counted against the route template")
+	}
+	if metrics.Requests("/users/{id}", "POST", http.StatusForbidden) != 1 {
+		t.Error("rejected method was not counted with its status")
+	}
+	if metrics.Requests("/users/{id}", rpc.OtherMethod, http.StatusForbidden) != 2 {
+		t.Error("unknown methods were not counted under a single label")
+	}
+	if metrics.Denials("/admin", fack.ReasonUnknownSource.ToString()) != 1 {
+		t.Error("auth denial was not counted with its reason")
+	}
+	if metrics.InFlight("/slow") != 1 {
+		t.Error("in-flight request was not reported by the gauge")
+	}
+
+	resp, err := http.Get(url + rpc.MetricsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	exposition := string(body)
+	for _, line := range []string{
+		"# TYPE fack_requests_total counter",
+		`fack_requests_total{route="/users/{id}",method="GET",status="200"} 2`,
+		`fack_requests_total{route="/users/{id}",method="OTHER",status="403"} 2`,
+		`fack_request_duration_seconds_bucket{route="/users/{id}",le="+Inf"} 5`,
+		`fack_request_duration_seconds_count{route="/users/{id}"} 5`,
+		`fack_auth_denials_total{route="/admin",reason="unknown_source"} 1`,
+		`fack_requests_in_flight{route="/slow"} 1`,
+	} {
+		if !strings.Contains(exposition, line+"\n") {
+			t.Errorf("exposition is missing %s", line)
+		}
+	}
+	if strings.Contains(exposition, "BREW") || strings.Contains(exposition, "brew") {
+		t.Error("a method sent by the client was used as a label")
+	}
+	if resp.Header.Get("Content-Type") != rpc.MetricsContentType {
+		t.Error("metrics were not served as the Prometheus text format")
+	}
+
+	close(release)
+	time.Sleep(ShortTimeout)
+	if metrics.InFlight("/slow") != 0 {
+		t.Error("finished request was still reported as in flight")
+	}
+}
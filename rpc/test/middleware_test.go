@@ -0,0 +1,110 @@
+package test
+
+import (
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	MiddlewarePort         = 8103
+	ReplacedMiddlewarePort = 8104
+)
+
+func trace(name string) fack.Middleware {
+	return func(next fack.Router) fack.Router {
+		return func(request fack.Request, response fack.Response) {
+			next(request, response)
+			response.Pair(name, true)
+		}
+	}
+}
+
+func requireParam(next fack.Router) fack.Router {
+	return func(request fack.Request, response fack.Response) {
+		if len(request.GetParams()) == 0 {
+			response.SetStatus(http.StatusTeapot).SetDescription("missing param")
+			return
+		}
+		next(request, response)
+	}
+}
+
+func TestNodeMiddlewareChain(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(MiddlewarePort))
+	node.Use(trace("node"))
+	node.Function("/chain", index).Method(fack.GET).Use(trace("route"), requireParam)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(MiddlewarePort)
+
+	request := rpc.NewRequest("/chain")
+	resp, err := request.Send("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != http.StatusTeapot {
+		t.Error("route middleware did not short-circuit the request")
+	}
+	if resp.GetData()["node"] != true {
+		t.Error("node middleware did not wrap the short-circuited route chain")
+	}
+
+	request.Param = []string{"value"}
+	resp, err = request.Send("GET", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (resp.GetStatus() != http.StatusOK) || (resp.GetData()["route"] != true) || (resp.GetData()["node"] != true) {
+		t.Error("node and route middleware did not both wrap the handler")
+	}
+}
+
+func TestNodeMiddlewareReplacesDefaults(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(ReplacedMiddlewarePort))
+
+	// the defaults are replaced without the JSON content check
+	node.Middleware(rpc.Recovery, rpc.MethodFilter, rpc.DecodeBody)
+	node.Function("/plain", index).Method(fack.GET)
+	node.Function("/secure", index).Method(fack.GET).Auth(true)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(ReplacedMiddlewarePort) + "/plain"
+
+	request, _ := http.NewRequest("GET", url, strings.NewReader("{}"))
+	request.Header.Set("Content-Type", "text/plain")
+	plain, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.StatusCode != http.StatusOK {
+		t.Errorf("removed JSON content check still rejected the request with %d", plain.StatusCode)
+	}
+
+	plain, err = http.Post(url, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.StatusCode != http.StatusForbidden {
+		t.Error("replaced chain did not keep the method filter")
+	}
+
+	// authentication is applied to protected routes even though the chain leaves it out
+	resp, err := rpc.NewRequest("/secure").Send("GET", LocalHost+fmt.Sprint(ReplacedMiddlewarePort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != http.StatusUnauthorized {
+		t.Errorf("a chain without Authentication served a protected route with %d", resp.GetStatus())
+	}
+}
@@ -12,6 +12,8 @@ import (
 
 const (
 	GETPort            = 8000
+	ShutdownPort       = 8101
+	ListenErrorPort    = 8102
 	SuccessMessage     = "success"
 	LocalHost          = "http://127.0.0.1:"
 	WaitForServerStart = 1000 * time.Millisecond
@@ -42,13 +44,13 @@ func TestAttemptAddRouteOutsideOfStartup(t *testing.T) {
 	n := rpc.NewNode(a, false)
 	n.Status(fack.Running) // simulate the n.Start() function
 
-	n.Function("/", index).Method(fack.GET)
-
 	defer func() {
 		if err := recover(); err != nil {
 			log.Println("SUCCESS panicked on bad function assignment", err)
 		}
 	}()
+
+	n.Function("/", index).Method(fack.GET)
 }
 
 func TestNodeReceivedNonJSONRequest(t *testing.T) {
@@ -61,7 +63,7 @@ func TestNodeReceivedNonJSONRequest(t *testing.T) {
 		t.Error("could not connect to node properly")
 	}
 
-	if rsp.StatusCode != http.StatusBadRequest {
+	if rsp.StatusCode != http.StatusUnsupportedMediaType {
 		t.Error("node is not properly rejecting non-json core")
 	}
 }
@@ -121,3 +123,61 @@ func TestNodeRequestToBlockedMethod(t *testing.T) {
 		t.Error("The node is accepting unwanted HTTP method types")
 	}
 }
+
+func TestNodeGracefulShutdown(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(ShutdownPort))
+	node.DrainTimeout(2 * time.Second)
+	node.Function("/slow", func(request fack.Request, response fack.Response) {
+		time.Sleep(500 * time.Millisecond)
+		response.SetStatus(http.StatusOK).SetDescription(SuccessMessage)
+	}).Method(fack.GET)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- node.Start()
+	}()
+	time.Sleep(WaitForServerStart)
+
+	// the in-flight request must be drained rather than dropped
+	inFlight := make(chan *rpc.Response, 1)
+	go func() {
+		resp, err := rpc.NewRequest("/slow").Send("GET", LocalHost+fmt.Sprint(ShutdownPort))
+		if err != nil {
+			t.Error(err)
+		}
+		inFlight <- resp
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	if err := node.Shutdown(); err != nil {
+		t.Errorf("shutdown did not drain within the deadline: %s", err.Error())
+	}
+
+	if resp := <-inFlight; (resp == nil) || (resp.GetStatus() != http.StatusOK) {
+		t.Error("in-flight request was not allowed to finish")
+	}
+
+	if err := <-stopped; err != nil {
+		t.Errorf("start returned an error after a graceful shutdown: %s", err.Error())
+	}
+
+	if node.GetStatus() != rpc.Killed {
+		t.Error("node was not moved to the Killed state")
+	}
+
+	if _, err := rpc.NewRequest("/slow").Send("GET", LocalHost+fmt.Sprint(ShutdownPort)); err == nil {
+		t.Error("node is still accepting connections after shutdown")
+	}
+}
+
+func TestNodeStartReturnsListenError(t *testing.T) {
+	first := rpc.NewNode(fack.LocalHost().SetPort(ListenErrorPort))
+	go first.Start()
+	defer first.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	second := rpc.NewNode(fack.LocalHost().SetPort(ListenErrorPort))
+	if err := second.Start(); err == nil {
+		t.Error("start swallowed the error of a port that is already in use")
+	}
+}
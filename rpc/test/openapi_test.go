@@ -0,0 +1,109 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const (
+	OpenAPIPort = 8110
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(OpenAPIPort), "users")
+	rpc.Handle(node, "/greet", greet).Method(fack.GET).Describe("Greets a user")
+	rpc.Handle(node, "/sum", sum).Method(fack.POST)
+	node.Function("/admin", index).Method(fack.GET).Method(fack.PULL).Auth(true).Timeout(time.Second)
+	node.ServeOpenAPI("1.2.0")
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	resp, err := http.Get(LocalHost + fmt.Sprint(OpenAPIPort) + rpc.OpenAPIPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var document map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		t.Fatal(err)
+	}
+
+	if (document["openapi"] != rpc.OpenAPIVersion) || (lookup(document, "info", "title") != "users") || (lookup(document, "info", "version") != "1.2.0") {
+		t.Errorf("document header was not generated: %v", document["info"])
+	}
+
+	greetOperation := lookup(document, "paths", "/greet", "get")
+	if (greetOperation == nil) || (lookup(document, "paths", "/greet", "post") != nil) {
+		t.Fatal("operations were not generated for the supported methods only")
+	}
+	if lookup(document, "paths", "/greet", "get", "summary") != "Greets a user" {
+		t.Error("route description was not used as the operation summary")
+	}
+
+	input := lookup(document, "paths", "/greet", "get", "requestBody", "content", "application/json", "schema", "allOf", 1, "properties", "args", "oneOf", 0)
+	if (lookup(input, "properties", "ID", "type") != "integer") || (lookup(input, "properties", "Shout", "type") != "boolean") {
+		t.Errorf("typed arguments were not documented: %v", input)
+	}
+	if required, _ := lookup(input, "required").([]any); len(required) != 2 {
+		t.Errorf("optional fields were documented as required: %v", required)
+	}
+	if lookup(input, "properties", "ignored") != nil {
+		t.Error("unexported fields were documented")
+	}
+
+	output := lookup(document, "paths", "/greet", "get", "responses", "200", "content", "application/json", "schema", "allOf", 1, "properties", "data")
+	if lookup(output, "properties", "greeting", "type") != "string" {
+		t.Errorf("typed result was not documented: %v", output)
+	}
+	if lookup(document, "paths", "/sum", "post", "responses", "200", "content", "application/json", "schema", "allOf", 1, "properties", "data", "properties", rpc.ResultKey, "type") != "number" {
+		t.Error("scalar result was not documented under the result key")
+	}
+
+	admin := lookup(document, "paths", "/admin", "get")
+	if (lookup(admin, "security", 0, rpc.SignatureScheme) == nil) || (lookup(admin, "responses", "401") == nil) {
+		t.Error("authenticated route did not declare the signature security scheme")
+	}
+	if (lookup(admin, "x-fack-timeout") != "1s") || (lookup(document, "paths", "/admin", "x-pull") == nil) {
+		t.Error("timeout or PULL method was not documented")
+	}
+	if lookup(admin, "requestBody", "content", "application/json", "schema", "$ref") != "#/components/schemas/Request" {
+		t.Error("untyped route did not reference the request envelope")
+	}
+	scheme := lookup(document, "components", "securitySchemes", rpc.SignatureScheme)
+	if (lookup(scheme, "type") != "apiKey") || (lookup(scheme, "in") == nil) || (lookup(scheme, "name") == nil) {
+		t.Errorf("signature security scheme was not declared as a valid OpenAPI scheme: %v", scheme)
+	}
+	if lookup(scheme, rpc.SignatureField, "signature") != "auth.signature" {
+		t.Error("signature security scheme did not describe where the signature is carried")
+	}
+}
+
+// lookup
+// Walks a decoded JSON document by object keys and array indices, returning nil if any is missing.
+func lookup(value any, keys ...any) any {
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil
+			}
+			value = object[k]
+		case int:
+			array, ok := value.([]any)
+			if !ok || (k >= len(array)) {
+				return nil
+			}
+			value = array[k]
+		}
+	}
+	return value
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const (
+	PathParamsPort = 8111
+	SignedPathPort = 8132
+)
+
+func echoPath(request fack.Request, response fack.Response) {
+	response.SetStatus(http.StatusOK).SetDescription(SuccessMessage).
+		Pair("id", request.PathParam("id")).
+		Pair("orderID", request.PathParam("orderID"))
+}
+
+func TestPathParameters(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(PathParamsPort))
+	node.Function("/users/{id}", echoPath).Method(fack.GET)
+	node.Function("/users/{id}/orders/{orderID}", echoPath).Method(fack.GET)
+	node.Function("/users/me", func(request fack.Request, response fack.Response) {
+		response.SetStatus(http.StatusOK).SetDescription("me")
+	}).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(PathParamsPort)
+
+	resp, err := rpc.NewRequest("/users/42").Send("GET", url)
+	if (err != nil) || (resp.GetStatus() != http.StatusOK) || (resp.GetData()["id"] != "42") {
+		t.Errorf("path parameter was not matched: %v", resp)
+	}
+
+	resp, err = rpc.NewRequest("/users/42/orders/7").Send("GET", url)
+	if (err != nil) || (resp.GetData()["id"] != "42") || (resp.GetData()["orderID"] != "7") {
+		t.Errorf("nested path parameters were not matched: %v", resp)
+	}
+
+	resp, err = rpc.NewRequest("/users/me").Send("GET", url)
+	if (err != nil) || (resp.GetDescription() != "me") {
+		t.Error("literal path did not take precedence over the template")
+	}
+
+	if unmatched, err := http.Get(url + "/users/42/orders"); (err != nil) || (unmatched.StatusCode != http.StatusNotFound) {
+		t.Error("a path that does not match any template was served")
+	}
+}
+
+func TestConflictingTemplates(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(PathParamsPort))
+	node.Function("/users/{id}", echoPath)
+
+	for _, path := range []string{"/users/{name}", "/users/{id", "/pairs/{a}/{a}"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %s did not panic", path)
+				}
+			}()
+			node.Function(path, echoPath)
+		}()
+	}
+
+	if len(node.Routes()) != 1 {
+		t.Error("a rejected path was added to the route registry")
+	}
+}
+
+func TestSignedFunctionMatchesPath(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("could not generate an ECDSA key pair")
+	}
+	endpoint := fack.NewEndpoint("alice", &key.PublicKey)
+	endpoint.AddLocalPermission("/users/{id}", fack.NewPermission().Enable(fack.GET))
+	auth := fack.NewAuth()
+	auth.AddTrusted("127.0.0.1", endpoint)
+
+	node := rpc.NewNode(fack.LocalHost().SetPort(SignedPathPort), auth)
+	node.Function("/users/{id}", echoPath).Method(fack.GET).Auth(true)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(SignedPathPort)
+
+	if resp, err := callFrom(url+"/users/42", "", signedBody(t, "/users/42", key)); (err != nil) || (resp.StatusCode != http.StatusOK) {
+		t.Errorf("request signed for the path it was sent to was rejected: %v", resp)
+	}
+
+	// the signature is valid, but for another user than the one in the path
+	if resp, err := callFrom(url+"/users/43", "", signedBody(t, "/users/42", key)); (err != nil) || (resp.StatusCode != http.StatusUnauthorized) {
+		t.Errorf("request signed for another path was accepted: %v", resp)
+	}
+	if node.Metrics().Denials("/users/{id}", fack.ReasonPathMismatch.ToString()) != 1 {
+		t.Error("path mismatch was not counted as a denial")
+	}
+}
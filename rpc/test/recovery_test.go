@@ -0,0 +1,155 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	RecoveryPort        = 8118
+	BareRecoveryPort    = 8119
+	UnencodablePort     = 8134
+	duplicatePairPanics = "the key already exists"
+)
+
+func duplicatePair(request fack.Request, response fack.Response) {
+	response.SetStatus(http.StatusOK).Pair("partial", true).Pair("partial", false)
+}
+
+func TestPanicRecovery(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(RecoveryPort))
+
+	var mutex sync.Mutex
+	reported := make([]error, 0)
+	node.OnError(func(request fack.Request, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reported = append(reported, err)
+		panic("the hook itself failed")
+	})
+
+	node.Function("/pair", duplicatePair).Method(fack.GET)
+	node.Function("/description", func(request fack.Request, response fack.Response) {
+		response.SetDescription("")
+	}).Method(fack.GET)
+	node.FunctionContext("/timed", func(ctx context.Context, request fack.Request, response fack.Response) {
+		duplicatePair(request, response)
+	}).Method(fack.GET).Timeout(time.Second)
+	rpc.Handle(node, "/typed", func(ctx context.Context, in []string) (string, error) {
+		return "", errors.New("database unavailable")
+	}).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(RecoveryPort)
+	for _, path := range []string{"/pair", "/description", "/timed"} {
+		resp, err := rpc.NewRequest(path).Send("GET", url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (resp.GetStatus() != http.StatusInternalServerError) || (resp.GetDescription() != rpc.NodePanic) || (len(resp.GetData()) != 0) {
+			t.Errorf("%s: panic did not return the error envelope: %+v", path, resp)
+		}
+		if len(resp.RequestID) == 0 {
+			t.Errorf("%s: error envelope did not carry the request ID", path)
+		}
+	}
+
+	resp, err := rpc.NewRequest("/typed").Send("GET", url)
+	if (err != nil) || (resp.GetStatus() != http.StatusInternalServerError) || (resp.GetDescription() != rpc.Failure) {
+		t.Errorf("typed handler error was not returned as a generic 500: %v", resp)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(reported) != 4 {
+		t.Fatalf("expected 4 reported errors, got %d", len(reported))
+	}
+
+	var panicErr *rpc.PanicError
+	if !errors.As(reported[0], &panicErr) || (panicErr.Value != duplicatePairPanics) || !strings.Contains(string(panicErr.Stack), "duplicatePair") {
+		t.Errorf("panic was not reported with its value and stack: %v", reported[0])
+	}
+	if !errors.As(reported[2], &panicErr) || !strings.Contains(string(panicErr.Stack), "duplicatePair") {
+		t.Error("panic on the goroutine of a timed handler lost its stack")
+	}
+	if reported[3].Error() != "database unavailable" {
+		t.Error("typed handler error was not reported")
+	}
+}
+
+func TestPanicRecoveryWithoutMiddleware(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(BareRecoveryPort))
+	node.Middleware() // not even the Recovery middleware is left in the chain
+	node.Function("/pair", duplicatePair)
+
+	reported := make(chan error, 1)
+	node.OnError(func(request fack.Request, err error) {
+		reported <- err
+	})
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	resp, err := rpc.NewRequest("/pair").Send("GET", LocalHost+fmt.Sprint(BareRecoveryPort))
+	if (err != nil) || (resp.GetStatus() != http.StatusInternalServerError) || (resp.GetDescription() != rpc.NodePanic) {
+		t.Errorf("panic outside of the Recovery middleware dropped the connection: %v %v", resp, err)
+	}
+
+	select {
+	case err := <-reported:
+		var panicErr *rpc.PanicError
+		if !errors.As(err, &panicErr) {
+			t.Error("panic was not reported to the error hook")
+		}
+	case <-time.After(time.Second):
+		t.Error("panic was not reported to the error hook")
+	}
+}
+
+func TestUnencodableResponse(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(UnencodablePort))
+	node.Function("/channel", func(request fack.Request, response fack.Response) {
+		response.SetStatus(http.StatusOK).Pair("channel", make(chan int))
+	}).Method(fack.GET)
+
+	reported := make(chan error, 1)
+	node.OnError(func(request fack.Request, err error) {
+		reported <- err
+	})
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	resp, err := rpc.NewRequest("/channel").Send("GET", LocalHost+fmt.Sprint(UnencodablePort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (resp.GetStatus() != http.StatusInternalServerError) || (resp.GetDescription() != rpc.Failure) || (len(resp.GetData()) != 0) {
+		t.Errorf("response that could not be encoded was not returned as a 500: %+v", resp)
+	}
+	if len(resp.RequestID) == 0 {
+		t.Error("error envelope did not carry the request ID")
+	}
+
+	select {
+	case err := <-reported:
+		if err == nil {
+			t.Error("encoding error was not reported to the error hook")
+		}
+	case <-time.After(time.Second):
+		t.Error("encoding error was not reported to the error hook")
+	}
+}
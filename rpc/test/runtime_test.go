@@ -0,0 +1,130 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const (
+	RuntimePort = 8113
+)
+
+func describe(description string) fack.ContextRouter {
+	return func(ctx context.Context, request fack.Request, response fack.Response) {
+		response.SetStatus(http.StatusOK).SetDescription(description)
+	}
+}
+
+func TestRuntimeFunctions(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(RuntimePort))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	node.FunctionContext("/plugin", func(ctx context.Context, request fack.Request, response fack.Response) {
+		close(started)
+		<-release
+		response.SetStatus(http.StatusOK).SetDescription("v1")
+	}).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(RuntimePort)
+
+	if err := node.AddFunction(fack.NewRoute("/added").Method(fack.GET), describe("added")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rpc.NewRequest("/added").Send("GET", url)
+	if (err != nil) || (resp.GetDescription() != "added") {
+		t.Error("function added while running was not served")
+	}
+
+	var conflict *fack.RouteConflictError
+	if err := node.AddFunction(fack.NewRoute("/added"), describe("again")); !errors.As(err, &conflict) {
+		t.Error("adding a registered path did not return a conflict")
+	}
+
+	// a request in flight on the old handler finishes on it after the function is replaced
+	inFlight := make(chan *rpc.Response, 1)
+	go func() {
+		resp, _ := rpc.NewRequest("/plugin").Send("GET", url)
+		inFlight <- resp
+	}()
+	<-started
+
+	if err := node.ReplaceFunction(fack.NewRoute("/plugin").Method(fack.GET).Method(fack.POST), describe("v2")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = rpc.NewRequest("/plugin").Send("POST", url)
+	if (err != nil) || (resp.GetDescription() != "v2") {
+		t.Error("replaced function was not served by the new handler and route")
+	}
+
+	close(release)
+	if resp = <-inFlight; (resp == nil) || (resp.GetDescription() != "v1") {
+		t.Errorf("in-flight request did not finish on the old handler: %v", resp)
+	}
+
+	if err := node.RemoveFunction("/added"); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := http.Get(url + "/added"); (err != nil) || (removed.StatusCode != http.StatusNotFound) {
+		t.Error("removed function was still served")
+	}
+
+	var notFound *fack.RouteNotFoundError
+	if err := node.RemoveFunction("/added"); !errors.As(err, &notFound) {
+		t.Error("removing an unregistered path did not return an error")
+	}
+	if err := node.ReplaceFunction(fack.NewRoute("/missing"), describe("missing")); !errors.As(err, &notFound) {
+		t.Error("replacing an unregistered path did not return an error")
+	}
+
+	routes := node.Routes()
+	if (len(routes) != 1) || (routes[0].GetPath() != "/plugin") || !routes[0].IsMethodSupported(fack.POST) {
+		t.Error("route registry did not follow the runtime changes")
+	}
+}
+
+func TestRuntimeTemplateReplacement(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(RuntimePort))
+	node.Function("/users/{id}", echoPath)
+
+	if err := node.ReplaceFunction(fack.NewRoute("/users/{name}"), describe("renamed")); err != nil {
+		t.Fatal(err)
+	}
+	if routes := node.Routes(); (len(routes) != 1) || (routes[0].GetPath() != "/users/{name}") {
+		t.Error("template was not replaced by one of the same shape")
+	}
+	if err := node.RemoveFunction("/users/{name}"); (err != nil) || (len(node.Routes()) != 0) {
+		t.Error("template could not be removed")
+	}
+}
+
+func TestRuntimeMalformedTemplate(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(RuntimePort))
+	node.Function("/users/{id}", echoPath)
+
+	// a malformed template is returned as an error rather than panicking a running node
+	var malformed *fack.MalformedRouteError
+	if err := node.AddFunction(fack.NewRoute("/orders/{id"), describe("orders")); !errors.As(err, &malformed) {
+		t.Errorf("adding a malformed template did not return an error: %v", err)
+	}
+	if err := node.AddFunction(fack.NewRoute("/pairs/{id}/{id}"), describe("pairs")); !errors.As(err, &malformed) {
+		t.Errorf("adding a template with a repeated name did not return an error: %v", err)
+	}
+	if err := node.ReplaceFunction(fack.NewRoute("/users/{}"), describe("users")); !errors.As(err, &malformed) {
+		t.Errorf("replacing with a malformed template did not return an error: %v", err)
+	}
+
+	if routes := node.Routes(); (len(routes) != 1) || (routes[0].GetPath() != "/users/{id}") {
+		t.Error("a malformed template changed the route registry")
+	}
+}
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	TracePort   = 8117
+	Traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+)
+
+func TestTracePropagation(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(TracePort))
+	url := LocalHost + fmt.Sprint(TracePort)
+
+	downstream := make(chan rpc.Trace, 1)
+	node.Function("/b", func(request fack.Request, response fack.Response) {
+		downstream <- request.(*rpc.Request).Trace()
+		response.SetStatus(http.StatusOK)
+	}).Method(fack.GET)
+
+	node.FunctionContext("/a", func(ctx context.Context, request fack.Request, response fack.Response) {
+		trace, _ := rpc.TraceFromContext(ctx)
+		resp, err := rpc.NewRequest("/b").SendContext(ctx, "GET", url)
+		if (err != nil) || (resp.RequestID != request.RequestID()) {
+			t.Error("downstream call did not carry the request ID")
+		}
+		response.SetStatus(http.StatusOK).Pair("span", trace.SpanID).Pair("trace", trace.TraceID)
+	}).Method(fack.GET)
+
+	// a plain handler sends on behalf of its request without handling a context
+	node.Function("/c", func(request fack.Request, response fack.Response) {
+		resp, err := request.(*rpc.Request).Call("/b").Send("GET", url)
+		if (err != nil) || (resp.RequestID != request.RequestID()) {
+			t.Error("call sent from a handler did not carry the request ID")
+		}
+		response.SetStatus(http.StatusOK).Pair("span", request.(*rpc.Request).Trace().SpanID)
+	}).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	httpRequest, _ := http.NewRequest("GET", url+"/a", bytes.NewReader(rpc.NewRequest("/a").Bytes()))
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set(rpc.RequestIDHeader, "checkout-1234")
+	httpRequest.Header.Set(rpc.TraceparentHeader, Traceparent)
+
+	httpResponse, err := http.DefaultClient.Do(httpRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpResponse.Body.Close()
+
+	upstream := rpc.NewResponse()
+	json.NewDecoder(httpResponse.Body).Decode(upstream)
+	if httpResponse.Header.Get(rpc.RequestIDHeader) != "checkout-1234" {
+		t.Error("request ID sent by the client was not echoed")
+	}
+	if !strings.HasPrefix(httpResponse.Header.Get(rpc.TraceparentHeader), "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Error("trace ID sent by the client was not continued")
+	}
+
+	trace := <-downstream
+	if (trace.RequestID != "checkout-1234") || (trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Errorf("trace was not propagated to the downstream call: %+v", trace)
+	}
+	if trace.ParentID != upstream.GetData()["span"] {
+		t.Errorf("downstream call was not parented to the span of the handler: %+v", trace)
+	}
+
+	// a client without trace headers is given a new request ID, echoed in the Response
+	resp, err := rpc.NewRequest("/b").Send("GET", url)
+	if (err != nil) || (len(resp.RequestID) != 32) || !strings.HasPrefix(resp.Traceparent, "00-") {
+		t.Errorf("generated request ID was not echoed in the response: %+v", resp)
+	}
+	if trace = <-downstream; (trace.RequestID != resp.RequestID) || (len(trace.ParentID) != 0) {
+		t.Errorf("handler did not see the generated trace: %+v", trace)
+	}
+
+	httpRequest, _ = http.NewRequest("GET", url+"/c", bytes.NewReader(rpc.NewRequest("/c").Bytes()))
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set(rpc.RequestIDHeader, "checkout-5678")
+	httpRequest.Header.Set(rpc.TraceparentHeader, Traceparent)
+	httpResponse, err = http.DefaultClient.Do(httpRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpResponse.Body.Close()
+
+	upstream = rpc.NewResponse()
+	json.NewDecoder(httpResponse.Body).Decode(upstream)
+	trace = <-downstream
+	if (trace.RequestID != "checkout-5678") || (trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Errorf("trace was not propagated by a call sent from a handler: %+v", trace)
+	}
+	if trace.ParentID != upstream.GetData()["span"] {
+		t.Errorf("call sent from a handler was not parented to its span: %+v", trace)
+	}
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	TypedPort = 8107
+)
+
+type GreetInput struct {
+	ID      int
+	Name    string
+	Shout   bool `param:",optional"`
+	ignored string
+}
+
+type GreetOutput struct {
+	Greeting string `json:"greeting"`
+	ID       int    `json:"id"`
+}
+
+func greet(ctx context.Context, in GreetInput) (GreetOutput, error) {
+	if in.ID == 0 {
+		return GreetOutput{}, rpc.NewError(http.StatusNotFound, "no user with id 0")
+	}
+
+	greeting := "hello " + in.Name
+	if in.Shout {
+		greeting = strings.ToUpper(greeting)
+	}
+	return GreetOutput{Greeting: greeting, ID: in.ID}, nil
+}
+
+func sum(ctx context.Context, in []float64) (float64, error) {
+	total := 0.0
+	for _, f := range in {
+		total += f
+	}
+	return total, nil
+}
+
+func TestTypedHandlers(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(TypedPort))
+	rpc.Handle(node, "/greet", greet).Method(fack.GET)
+	rpc.Handle(node, "/sum", sum).Method(fack.GET)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	url := LocalHost + fmt.Sprint(TypedPort)
+	send := func(function string, params ...string) *rpc.Response {
+		request := rpc.NewRequest(function)
+		request.Param = params
+		resp, err := request.Send("GET", url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := send("/greet", "42", "ada", "true")
+	if (resp.GetStatus() != http.StatusOK) || (resp.GetData()["greeting"] != "HELLO ADA") || (resp.GetData()["id"] != 42.0) {
+		t.Errorf("typed output was not encoded into the response: %v", resp)
+	}
+
+	if resp = send("/greet", "42", "ada"); resp.GetData()["greeting"] != "hello ada" {
+		t.Error("optional field could not be left out")
+	}
+
+	if resp = send("/greet", "42"); (resp.GetStatus() != http.StatusBadRequest) || (resp.GetDescription() != rpc.SyntaxMismatch) {
+		t.Error("missing param did not return a syntax mismatch")
+	}
+
+	if resp = send("/greet", "forty-two", "ada"); (resp.GetStatus() != http.StatusBadRequest) || (resp.GetDescription() != rpc.BadArgument) {
+		t.Error("unconvertible param did not return a bad argument")
+	}
+
+	if resp = send("/greet", "0", "nobody"); resp.GetStatus() != http.StatusNotFound {
+		t.Error("handler error did not choose the response status")
+	}
+
+	if resp = send("/sum", "1.5", "2", "0.5"); resp.GetData()[rpc.ResultKey] != 4.0 {
+		t.Errorf("scalar output was not stored under the result key: %v", resp.GetData())
+	}
+}
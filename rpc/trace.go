@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	RequestIDHeader    = "X-Request-Id"
+	TraceparentHeader  = "traceparent"
+	traceVersion       = "00"
+	sampledFlags       = "01"
+	maxRequestIDLength = 128
+	traceIDLength      = 32
+	spanIDLength       = 16
+	invalidTraceID     = "00000000000000000000000000000000"
+	invalidSpanID      = "0000000000000000"
+)
+
+// Trace
+// The request ID and W3C trace context of a call. TraceID is shared by every call made on behalf
+// of the original request, SpanID identifies this call and ParentID the call that made it.
+type Trace struct {
+	RequestID string
+	TraceID   string
+	SpanID    string
+	ParentID  string
+	Flags     string
+}
+
+// Traceparent
+// The W3C traceparent header identifying this call as the parent of the calls it makes.
+func (trace Trace) Traceparent() string {
+	return traceVersion + "-" + trace.TraceID + "-" + trace.SpanID + "-" + trace.Flags
+}
+
+// NewTrace
+// Continues the trace of the incoming HTTP request, accepting its request ID and traceparent
+// when they are well-formed and starting a new trace (or request ID) when they are not.
+func NewTrace(r *http.Request) Trace {
+	trace := Trace{RequestID: r.Header.Get(RequestIDHeader), SpanID: randomHex(spanIDLength / 2), Flags: sampledFlags}
+	if !isValidRequestID(trace.RequestID) {
+		trace.RequestID = randomHex(16)
+	}
+
+	if traceID, parentID, flags, ok := parseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+		trace.TraceID = traceID
+		trace.ParentID = parentID
+		trace.Flags = flags
+	} else {
+		trace.TraceID = randomHex(traceIDLength / 2)
+	}
+
+	return trace
+}
+
+// parseTraceparent
+// Only version 00 is understood, the IDs must be lowercase hex and not all zeros.
+func parseTraceparent(header string) (traceID, parentID, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if (len(parts) != 4) || (parts[0] != traceVersion) {
+		return "", "", "", false
+	}
+
+	if !isHex(parts[1], traceIDLength) || !isHex(parts[2], spanIDLength) || !isHex(parts[3], 2) {
+		return "", "", "", false
+	}
+	if (parts[1] == invalidTraceID) || (parts[2] == invalidSpanID) {
+		return "", "", "", false
+	}
+
+	return parts[1], parts[2], parts[3], true
+}
+
+func isHex(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for _, c := range value {
+		if !(((c >= '0') && (c <= '9')) || ((c >= 'a') && (c <= 'f'))) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidRequestID
+// A request ID chosen by the client is accepted if it is printable ASCII of a bounded length,
+// so that it cannot forge log lines or headers.
+func isValidRequestID(id string) bool {
+	if (len(id) == 0) || (len(id) > maxRequestIDLength) {
+		return false
+	}
+	for _, c := range id {
+		if (c < '!') || (c > '~') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	bytes := make([]byte, n)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+type traceKey struct{}
+
+// WithTrace
+// Attaches the trace to the context, requests sent with the context propagate it.
+func WithTrace(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+func TraceFromContext(ctx context.Context) (Trace, bool) {
+	trace, ok := ctx.Value(traceKey{}).(Trace)
+	return trace, ok
+}
+
+// propagate
+// Adds the request ID and a traceparent naming the current call as the parent to an outgoing
+// HTTP request.
+func propagate(ctx context.Context, r *http.Request) {
+	trace, ok := TraceFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	r.Header.Set(RequestIDHeader, trace.RequestID)
+	r.Header.Set(TraceparentHeader, trace.Traceparent())
+}
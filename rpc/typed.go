@@ -0,0 +1,248 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	paramTag      = "param"
+	paramOptional = "optional"
+	paramSkip     = "-"
+	ResultKey     = "result"
+)
+
+// Error
+// Returned by a typed handler to choose the status and description sent to the client, any
+// other error is sent as a 500 with a generic description and reported to the ErrorHook.
+type Error struct {
+	Status      int
+	Description string
+}
+
+func NewError(status int, description string) *Error {
+	return &Error{Status: status, Description: description}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s", e.Status, e.Description)
+}
+
+// Handle
+// Registers a typed handler on the node (or a Group of its routes). The request arguments (or legacy params) are decoded
+// into In before the handler is called, and the Out it returns is encoded into Response.Data.
+//
+// A struct In receives one positional argument per exported field in declaration order, or the
+// named argument matching the JSON name of each field. A field tagged `param:"-"` is skipped and
+// `param:",optional"` may be left out by the client. Any other In receives the first argument,
+// or every argument if it is a slice. An argument count that does not match returns
+// SyntaxMismatch and an argument that cannot be converted returns BadArgument.
+//
+// A struct or map Out is flattened into Response.Data by its JSON encoding, any other Out is
+// stored under the "result" key.
+func Handle[In, Out any](registrar Registrar, path string, handler func(ctx context.Context, in In) (Out, error)) *fack.Route {
+	return registrar.FunctionContext(path, func(ctx context.Context, request fack.Request, response fack.Response) {
+		var in In
+		if err := decodeArgs(request, &in); err != nil {
+			response.SetStatus(http.StatusBadRequest).SetDescription(err.Error())
+			return
+		}
+
+		out, err := handler(ctx, in)
+		if err != nil {
+			var e *Error
+			if errors.As(err, &e) {
+				response.SetStatus(e.Status).SetDescription(e.Description)
+			} else {
+				// the error may describe internals of the handler, it is only reported to the node
+				if r, ok := request.(*Request); ok {
+					r.node.report(request, err)
+				}
+				response.SetStatus(http.StatusInternalServerError).SetDescription(Failure)
+			}
+			return
+		}
+
+		if err := encodeResult(out, response); err != nil {
+			response.SetStatus(http.StatusInternalServerError).SetDescription(Failure)
+			return
+		}
+		response.SetStatus(http.StatusOK).SetDescription(Success)
+	}).Schema(reflect.TypeOf((*In)(nil)).Elem(), reflect.TypeOf((*Out)(nil)).Elem())
+}
+
+func decodeArgs(request fack.Request, in any) error {
+	value := reflect.ValueOf(in).Elem()
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return decodeStruct(request, value)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), request.ArgCount(), request.ArgCount())
+		for i := 0; i < request.ArgCount(); i++ {
+			if err := decodeArgument(request.Arg(i), slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	default:
+		if request.ArgCount() != 1 {
+			return errors.New(SyntaxMismatch)
+		}
+		return decodeArgument(request.Arg(0), value)
+	}
+}
+
+type paramField struct {
+	name     string
+	value    reflect.Value
+	optional bool
+}
+
+func decodeStruct(request fack.Request, value reflect.Value) error {
+	fields := make([]paramField, 0)
+	required := 0
+	named := false
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get(paramTag)
+		if tag == paramSkip {
+			continue
+		}
+
+		name := field.Name
+		if jsonName := strings.Split(field.Tag.Get("json"), ",")[0]; (len(jsonName) > 0) && (jsonName != "-") {
+			name = jsonName
+		}
+		named = named || request.NamedArg(name).Exists()
+
+		optional := strings.Contains(tag, paramOptional)
+		if !optional {
+			// optional fields may only follow the required fields
+			if required != len(fields) {
+				return errors.New(SyntaxMismatch)
+			}
+			required++
+		}
+		fields = append(fields, paramField{name: name, value: value.Field(i), optional: optional})
+	}
+
+	// named arguments are matched to fields by their JSON name, or the field name
+	if named {
+		for _, field := range fields {
+			argument := request.NamedArg(field.name)
+			if !argument.Exists() {
+				if field.optional {
+					continue
+				}
+				return errors.New(SyntaxMismatch)
+			}
+			if err := decodeArgument(argument, field.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if (request.ArgCount() < required) || (request.ArgCount() > len(fields)) {
+		return errors.New(SyntaxMismatch)
+	}
+
+	for i := 0; i < request.ArgCount(); i++ {
+		if err := decodeArgument(request.Arg(i), fields[i].value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// decodeArgument
+// Scalar arguments (including the strings sent by legacy clients) are converted from their
+// string form, anything else is decoded through its JSON encoding.
+func decodeArgument(argument fack.Argument, value reflect.Value) error {
+	if s, err := argument.String(); err == nil {
+		return decodeParam(s, value)
+	}
+	if err := argument.Decode(value.Addr().Interface()); err != nil {
+		return errors.New(BadArgument)
+	}
+	return nil
+}
+
+func decodeParam(param string, value reflect.Value) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(param)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(param)
+		if err != nil {
+			return errors.New(BadArgument)
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(param, Decimal, value.Type().Bits())
+		if err != nil {
+			return errors.New(BadArgument)
+		}
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(param, Decimal, value.Type().Bits())
+		if err != nil {
+			return errors.New(BadArgument)
+		}
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(param, value.Type().Bits())
+		if err != nil {
+			return errors.New(BadArgument)
+		}
+		value.SetFloat(f)
+	default:
+		// anything else is expected to be a JSON encoded param (ex. a nested struct)
+		if err := json.Unmarshal([]byte(param), value.Addr().Interface()); err != nil {
+			return errors.New(BadArgument)
+		}
+	}
+	return nil
+}
+
+func encodeResult(out any, response fack.Response) error {
+	value := reflect.ValueOf(out)
+	for (value.Kind() == reflect.Pointer) && !value.IsNil() {
+		value = value.Elem()
+	}
+
+	if (value.Kind() != reflect.Struct) && (value.Kind() != reflect.Map) {
+		response.Pair(ResultKey, out)
+		return nil
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]any)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, field := range fields {
+		response.Pair(key, field)
+	}
+
+	return nil
+}
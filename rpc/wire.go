@@ -0,0 +1,607 @@
+package rpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// the binary codecs share a single model of the values they exchange: nil, bool, int64, uint64,
+// float64, string, []byte, []any and map[string]any. Go values are converted to and from it by
+// the same rules (and json struct tags) that encoding/json follows, so a type encodes to the same
+// fields whichever codec it travels through.
+
+const maxWireDepth = 512
+
+var (
+	errWireTruncated = errors.New("the encoded value is truncated")
+	errWireDepth     = errors.New("the encoded value is nested too deeply")
+)
+
+// wireMarshaler
+// Implemented by types that choose their own value in the binary codecs, as json.Marshaler does.
+type wireMarshaler interface {
+	marshalWire() (any, error)
+}
+
+type wireUnmarshaler interface {
+	unmarshalWire(value any) error
+}
+
+var (
+	numberType          = reflect.TypeOf(json.Number(""))
+	wireMarshalerType   = reflect.TypeOf((*wireMarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+type wireField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var wireFieldCache sync.Map
+
+// wireFields
+// The fields of the struct type as encoding/json names them, embedded structs without a name
+// in their tag are flattened into the struct embedding them.
+func wireFields(t reflect.Type) []wireField {
+	if cached, found := wireFieldCache.Load(t); found {
+		return cached.([]wireField)
+	}
+
+	fields := make([]wireField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && (name == "") && (field.Type.Kind() == reflect.Struct) {
+			for _, embedded := range wireFields(field.Type) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, wireField{name: name, index: []int{i}, omitEmpty: strings.Contains(options, "omitempty")})
+	}
+
+	wireFieldCache.Store(t, fields)
+	return fields
+}
+
+func findWireField(fields []wireField, name string) (wireField, bool) {
+	for _, field := range fields {
+		if field.name == name {
+			return field, true
+		}
+	}
+	// like encoding/json, a key that differs only in case still matches the field
+	for _, field := range fields {
+		if strings.EqualFold(field.name, name) {
+			return field, true
+		}
+	}
+	return wireField{}, false
+}
+
+func isEmptyWireValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// toWire
+// Converts a Go value into the value model of the binary codecs.
+func toWire(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if v.Type() == numberType {
+		return wireNumber(json.Number(v.String()))
+	}
+
+	if v.Type().Implements(wireMarshalerType) {
+		if (v.Kind() == reflect.Pointer) && v.IsNil() {
+			return nil, nil
+		}
+		return v.Interface().(wireMarshaler).marshalWire()
+	}
+
+	// any other type with its own JSON encoding (ex. time.Time) is carried as that encoding
+	if v.Type().Implements(jsonMarshalerType) {
+		if (v.Kind() == reflect.Pointer) && v.IsNil() {
+			return nil, nil
+		}
+		data, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if err := unmarshalNumbers(data, &value); err != nil {
+			return nil, err
+		}
+		return toWire(reflect.ValueOf(value))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toWire(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return append([]byte(nil), v.Bytes()...), nil
+		}
+		return sliceToWire(v)
+	case reflect.Array:
+		return sliceToWire(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return mapToWire(v)
+	case reflect.Struct:
+		return structToWire(v)
+	}
+
+	return nil, fmt.Errorf("values of type %s cannot be encoded", v.Type())
+}
+
+func sliceToWire(v reflect.Value) (any, error) {
+	items := make([]any, v.Len())
+	for i := range items {
+		item, err := toWire(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func mapToWire(v reflect.Value) (any, error) {
+	fields := make(map[string]any, v.Len())
+
+	iterator := v.MapRange()
+	for iterator.Next() {
+		var key string
+		switch k := iterator.Key(); k.Kind() {
+		case reflect.String:
+			key = k.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			key = strconv.FormatInt(k.Int(), Decimal)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			key = strconv.FormatUint(k.Uint(), Decimal)
+		default:
+			return nil, fmt.Errorf("maps with keys of type %s cannot be encoded", k.Type())
+		}
+
+		value, err := toWire(iterator.Value())
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
+
+func structToWire(v reflect.Value) (any, error) {
+	fields := wireFields(v.Type())
+
+	encoded := make(map[string]any, len(fields))
+	for _, field := range fields {
+		value := v.FieldByIndex(field.index)
+		if field.omitEmpty && isEmptyWireValue(value) {
+			continue
+		}
+
+		item, err := toWire(value)
+		if err != nil {
+			return nil, err
+		}
+		encoded[field.name] = item
+	}
+	return encoded, nil
+}
+
+// wireNumber
+// Arguments decoded from JSON hold json.Number, which is carried as an integer when it is one.
+func wireNumber(number json.Number) (any, error) {
+	if i, err := number.Int64(); err == nil {
+		return i, nil
+	}
+	if u, err := strconv.ParseUint(string(number), Decimal, 64); err == nil {
+		return u, nil
+	}
+	return number.Float64()
+}
+
+// bindWire
+// Assigns a decoded value to the non-nil pointer v, strict binding rejects unknown struct fields.
+func bindWire(value any, v any, strict bool) error {
+	target := reflect.ValueOf(v)
+	if (target.Kind() != reflect.Pointer) || target.IsNil() {
+		return errors.New("values can only be decoded into a non-nil pointer")
+	}
+	return fromWire(value, target.Elem(), strict)
+}
+
+func fromWire(value any, v reflect.Value, strict bool) error {
+	if (v.Kind() != reflect.Pointer) && v.CanAddr() {
+		if unmarshaler, ok := v.Addr().Interface().(wireUnmarshaler); ok {
+			return unmarshaler.unmarshalWire(value)
+		}
+	}
+
+	if value == nil {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return fromWire(value, v.Elem(), strict)
+	}
+
+	if v.Type() == numberType {
+		if number, ok := toJSONNumber(value); ok {
+			v.SetString(string(number))
+			return nil
+		}
+		return mismatchWire(value, v)
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(jsonUnmarshalerType) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(data)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(value))
+			return nil
+		}
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			v.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := wireInt(value); ok && !v.OverflowInt(i) {
+			v.SetInt(i)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u, ok := wireUint(value); ok && !v.OverflowUint(u) {
+			v.SetUint(u)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := wireFloat(value); ok {
+			v.SetFloat(f)
+			return nil
+		}
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			v.SetString(s)
+			return nil
+		}
+	case reflect.Slice:
+		return sliceFromWire(value, v, strict)
+	case reflect.Array:
+		if items, ok := value.([]any); ok {
+			for i := 0; i < v.Len(); i++ {
+				if i < len(items) {
+					if err := fromWire(items[i], v.Index(i), strict); err != nil {
+						return err
+					}
+				} else {
+					v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		return mapFromWire(value, v, strict)
+	case reflect.Struct:
+		return structFromWire(value, v, strict)
+	}
+
+	return mismatchWire(value, v)
+}
+
+func sliceFromWire(value any, v reflect.Value, strict bool) error {
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		switch data := value.(type) {
+		case []byte:
+			v.SetBytes(append([]byte(nil), data...))
+			return nil
+		case string:
+			// a byte slice that travelled through JSON arrives as its base64 encoding
+			decoded, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return mismatchWire(value, v)
+			}
+			v.SetBytes(decoded)
+			return nil
+		}
+	}
+
+	items, ok := value.([]any)
+	if !ok {
+		return mismatchWire(value, v)
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := fromWire(item, slice.Index(i), strict); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+func mapFromWire(value any, v reflect.Value, strict bool) error {
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return mismatchWire(value, v)
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(fields)))
+	}
+
+	keyType := v.Type().Key()
+	for name, item := range fields {
+		key := reflect.New(keyType).Elem()
+		switch keyType.Kind() {
+		case reflect.String:
+			key.SetString(name)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := strconv.ParseInt(name, Decimal, 64)
+			if (err != nil) || key.OverflowInt(i) {
+				return mismatchWire(name, key)
+			}
+			key.SetInt(i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u, err := strconv.ParseUint(name, Decimal, 64)
+			if (err != nil) || key.OverflowUint(u) {
+				return mismatchWire(name, key)
+			}
+			key.SetUint(u)
+		default:
+			return mismatchWire(value, v)
+		}
+
+		element := reflect.New(v.Type().Elem()).Elem()
+		if err := fromWire(item, element, strict); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, element)
+	}
+	return nil
+}
+
+func structFromWire(value any, v reflect.Value, strict bool) error {
+	encoded, ok := value.(map[string]any)
+	if !ok {
+		return mismatchWire(value, v)
+	}
+
+	fields := wireFields(v.Type())
+
+	// decoded in a stable order so that the first error reported is always the same
+	names := make([]string, 0, len(encoded))
+	for name := range encoded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field, found := findWireField(fields, name)
+		if !found {
+			if strict {
+				return fmt.Errorf("unknown field %q", name)
+			}
+			continue
+		}
+		if err := fromWire(encoded[name], v.FieldByIndex(field.index), strict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mismatchWire(value any, v reflect.Value) error {
+	return fmt.Errorf("cannot decode a value of type %T into %s", value, v.Type())
+}
+
+func wireInt(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float64:
+		return int64(v), (v == math.Trunc(v)) && (v >= math.MinInt64) && (v < math.MaxInt64)
+	}
+	return 0, false
+}
+
+func wireUint(value any) (uint64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return uint64(v), v >= 0
+	case uint64:
+		return v, true
+	case float64:
+		return uint64(v), (v == math.Trunc(v)) && (v >= 0) && (v < math.MaxUint64)
+	}
+	return 0, false
+}
+
+func wireFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func toJSONNumber(value any) (json.Number, bool) {
+	switch v := value.(type) {
+	case int64:
+		return json.Number(strconv.FormatInt(v, Decimal)), true
+	case uint64:
+		return json.Number(strconv.FormatUint(v, Decimal)), true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", false
+		}
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64)), true
+	case string:
+		number := json.Number(v)
+		if _, err := number.Float64(); err == nil {
+			return number, true
+		}
+	}
+	return "", false
+}
+
+// wireReader
+// Reads the big-endian encodings that MessagePack and CBOR have in common.
+type wireReader struct {
+	data   []byte
+	offset int
+}
+
+func (reader *wireReader) remaining() int {
+	return len(reader.data) - reader.offset
+}
+
+func (reader *wireReader) readByte() (byte, error) {
+	if reader.remaining() < 1 {
+		return 0, errWireTruncated
+	}
+	b := reader.data[reader.offset]
+	reader.offset++
+	return b, nil
+}
+
+func (reader *wireReader) peek() (byte, error) {
+	if reader.remaining() < 1 {
+		return 0, errWireTruncated
+	}
+	return reader.data[reader.offset], nil
+}
+
+func (reader *wireReader) next(n int) ([]byte, error) {
+	if (n < 0) || (reader.remaining() < n) {
+		return nil, errWireTruncated
+	}
+	b := reader.data[reader.offset : reader.offset+n]
+	reader.offset += n
+	return b, nil
+}
+
+func (reader *wireReader) readUint(size int) (uint64, error) {
+	b, err := reader.next(size)
+	if err != nil {
+		return 0, err
+	}
+
+	var u uint64
+	for _, octet := range b {
+		u = (u << 8) | uint64(octet)
+	}
+	return u, nil
+}
+
+// length
+// Checks a declared length against the bytes left, each item takes at least size bytes, so that
+// a forged length cannot allocate more than the body holds.
+func (reader *wireReader) length(n uint64, size int) (int, error) {
+	if n > uint64(reader.remaining()/size) {
+		return 0, errWireTruncated
+	}
+	return int(n), nil
+}
+
+// wireInteger
+// Decoded integers are int64 unless they only fit an uint64.
+func wireInteger(u uint64) any {
+	if u <= math.MaxInt64 {
+		return int64(u)
+	}
+	return u
+}
+
+func sortedWireKeys(fields map[string]any) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -13,16 +13,11 @@ func (nodeStatus NodeStatus) ToString() string {
 	switch nodeStatus {
 	case 0:
 		return "Startup"
-		break
 	case 1:
 		return "Running"
-		break
 	case 2:
 		return "Frozen"
-		break
 	default:
 		return "Killed"
 	}
-
-	return "Killed"
 }
@@ -14,10 +14,16 @@ import (
 )
 
 const (
-	GETPort            = 8000
-	SuccessMessage     = "success"
-	LocalHost          = "http://127.0.0.1:"
-	WaitForServerStart = 2 * time.Second
+	GETPort = 8000
+	// every test starts its own node, so each needs a port of its own
+	NoPermissionsPort            = 8001
+	GlobalPermissionPort         = 8002
+	LocalPermissionPort          = 8003
+	GlobalAndLocalPermissionPort = 8004
+	TemplatePermissionPort       = 8006
+	SuccessMessage               = "success"
+	LocalHost                    = "http://127.0.0.1:"
+	WaitForServerStart           = 2 * time.Second
 )
 
 /*? Test Function */
@@ -81,7 +87,7 @@ func TestAuthNoGlobalOrLocalPermissionsPresent(t *testing.T) {
 	var ne *fack.Endpoint = fack.NewEndpoint("test", &privateKey.PublicKey)
 	na.AddTrusted("127.0.0.1", ne)
 
-	a := fack.LocalHost().SetPort(8000)
+	a := fack.LocalHost().SetPort(NoPermissionsPort)
 	n := rpc.NewNode(a, na) // pass a nil to logger pointer ~ no logging
 	route := n.Function("/", AuthenticatedIndex).Method(fack.GET)
 	route.RequiresAuth = true
@@ -98,7 +104,7 @@ func TestAuthNoGlobalOrLocalPermissionsPresent(t *testing.T) {
 	}
 
 	/* GET request should succeed */
-	resp, err := request.Send("GET", LocalHost+fmt.Sprint(GETPort))
+	resp, err := request.Send("GET", LocalHost+fmt.Sprint(NoPermissionsPort))
 	if err != nil {
 		t.Error("Failed to startup an HTTP GET route.")
 	}
@@ -123,7 +129,7 @@ func TestAuthGlobalPermissionPresent(t *testing.T) {
 	ne.AddGlobalPermission(&globalPermissionMap)
 	na.AddTrusted("127.0.0.1", ne)
 
-	a := fack.LocalHost().SetPort(8000)
+	a := fack.LocalHost().SetPort(GlobalPermissionPort)
 	n := rpc.NewNode(a, na) // pass a nil to logger pointer ~ no logging
 	route := n.Function("/", AuthenticatedIndex).Method(fack.GET)
 	route.RequiresAuth = true
@@ -140,7 +146,7 @@ func TestAuthGlobalPermissionPresent(t *testing.T) {
 	}
 
 	/* GET request should succeed */
-	resp, err := request.Send("GET", LocalHost+fmt.Sprint(GETPort))
+	resp, err := request.Send("GET", LocalHost+fmt.Sprint(GlobalPermissionPort))
 	if err != nil {
 		t.Error("Failed to startup an HTTP GET route.")
 	}
@@ -166,7 +172,7 @@ func TestAuthLocalPermissionPresent(t *testing.T) {
 	ne.AddLocalPermission("/", &localPermissionMap)
 	na.AddTrusted("127.0.0.1", ne)
 
-	a := fack.LocalHost().SetPort(8000)
+	a := fack.LocalHost().SetPort(LocalPermissionPort)
 	n := rpc.NewNode(a, na) // pass a nil to logger pointer ~ no logging
 	n.Function("/", AuthenticatedIndex).Method(fack.GET)
 
@@ -182,7 +188,7 @@ func TestAuthLocalPermissionPresent(t *testing.T) {
 	}
 
 	/* GET request should succeed */
-	resp, err := request.Send("GET", LocalHost+fmt.Sprint(GETPort))
+	resp, err := request.Send("GET", LocalHost+fmt.Sprint(LocalPermissionPort))
 	if err != nil {
 		t.Error("Failed to startup an HTTP GET route.")
 	}
@@ -201,7 +207,7 @@ func TestAuthGlobalAndLocalPermissionsPresent(t *testing.T) {
 		t.Error("Could not generate an ECDSA key pair")
 	}
 
-	address := fack.LocalHost().SetPort(8000)
+	address := fack.LocalHost().SetPort(GlobalAndLocalPermissionPort)
 
 	var auth *fack.Auth = fack.NewAuth()
 	node := rpc.NewNode(address, auth) // pass a nil to logger pointer ~ no logging
@@ -230,7 +236,7 @@ func TestAuthGlobalAndLocalPermissionsPresent(t *testing.T) {
 	}
 
 	/* GET request should succeed */
-	resp, err := request.Send("GET", LocalHost+fmt.Sprint(GETPort))
+	resp, err := request.Send("GET", LocalHost+fmt.Sprint(GlobalAndLocalPermissionPort))
 	if err != nil {
 		t.Error("Failed to startup an HTTP GET route.")
 	}
@@ -240,7 +246,7 @@ func TestAuthGlobalAndLocalPermissionsPresent(t *testing.T) {
 	}
 
 	/* POST request should NOT succeed */
-	resp, err = request.Send("POST", LocalHost+fmt.Sprint(GETPort))
+	resp, err = request.Send("POST", LocalHost+fmt.Sprint(GlobalAndLocalPermissionPort))
 	if err != nil {
 		t.Error("Failed to startup an HTTP GET route.")
 	}
@@ -250,3 +256,42 @@ func TestAuthGlobalAndLocalPermissionsPresent(t *testing.T) {
 	}
 
 }
+
+// local permissions are looked up against the route template rather than the path requested,
+// so a single permission covers every resource the template matches
+func TestAuthLocalPermissionOnTemplate(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Error("Could not generate an ECDSA key pair")
+	}
+
+	auth := fack.NewAuth()
+	endpoint := fack.NewEndpoint("test", &privateKey.PublicKey)
+	endpoint.AddLocalPermission("/accounts/{id}", fack.NewPermission().Enable(fack.GET))
+	auth.AddTrusted("127.0.0.1", endpoint)
+
+	node := rpc.NewNode(fack.LocalHost().SetPort(TemplatePermissionPort), auth)
+	node.Function("/accounts/{id}", AuthenticatedIndex).Method(fack.GET).Method(fack.DELETE).Auth(true)
+
+	go node.Start()
+	defer node.Shutdown()
+	time.Sleep(WaitForServerStart)
+
+	request := rpc.NewRequest("/accounts/7")
+	if err = fack.Sign(request, privateKey); err != nil {
+		t.Error(err.Error())
+	}
+
+	resp, err := request.Send("GET", LocalHost+fmt.Sprint(TemplatePermissionPort))
+	if (err != nil) || (resp.GetDescription() != "authenticated") {
+		t.Error("local permission on the template was not applied to the requested path")
+	}
+
+	if err = fack.Sign(request, privateKey); err != nil {
+		t.Error(err.Error())
+	}
+	resp, err = request.Send("DELETE", LocalHost+fmt.Sprint(TemplatePermissionPort))
+	if (err != nil) || (resp.GetStatus() != http.StatusUnauthorized) {
+		t.Error("Node was let into a permission the template does not grant")
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/GabeCordo/fack"
+	"net/http"
+	"testing"
+)
+
+func TestIsUsingJSONContent(t *testing.T) {
+	cases := []struct {
+		content string
+		json    bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"Application/JSON; charset=UTF-8", true},
+		{"application/json; charset=latin1", false},
+		{"application/jsonp", false},
+		{"text/plain", false},
+		{"application/json; =broken", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		r, _ := http.NewRequest(http.MethodPost, "http://127.0.0.1/", nil)
+		r.Header.Set("Content-Type", c.content)
+
+		if fack.IsUsingJSONContent(r) != c.json {
+			t.Errorf("content type %q: expected JSON content to be %v", c.content, c.json)
+		}
+	}
+}
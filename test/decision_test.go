@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"strings"
+	"testing"
+)
+
+func TestAuthorizeReasons(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("could not generate an ECDSA key pair")
+	}
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	auth := fack.NewAuth()
+	endpoint := fack.NewEndpoint("reports", &privateKey.PublicKey)
+	endpoint.AddGlobalPermission(fack.NewPermission().Enable(fack.GET))
+	auth.AddTrusted("127.0.0.1", endpoint)
+
+	sender := fack.EmptyAddress().SetHost("127.0.0.1")
+
+	signed := rpc.NewRequest("/export")
+	fack.Sign(signed, privateKey)
+
+	forged := rpc.NewRequest("/export")
+	fack.Sign(forged, otherKey)
+
+	stranger := fack.EmptyAddress().SetHost("10.0.0.1")
+
+	cases := []struct {
+		name    string
+		sender  *fack.Address
+		request *rpc.Request
+		method  fack.HTTPMethod
+		reason  fack.DecisionReason
+	}{
+		{"authorized", sender, signed, fack.GET, fack.ReasonAuthorized},
+		{"replayed", sender, signed, fack.GET, fack.ReasonStaleNonce},
+		{"unknown ip", stranger, signed, fack.GET, fack.ReasonUnknownSource},
+		{"method not in bitmap", sender, signed, fack.POST, fack.ReasonMethodNotPermitted},
+		{"wrong key", sender, forged, fack.GET, fack.ReasonSignatureMismatch},
+		{"unsigned", sender, rpc.NewRequest("/export"), fack.GET, fack.ReasonStaleNonce},
+	}
+
+	for _, c := range cases {
+		decision := auth.Authorize(c.sender, c.request, "/export", c.method)
+		if decision.Reason != c.reason {
+			t.Errorf("%s: expected %s, got %s", c.name, c.reason.ToString(), decision.Reason.ToString())
+		}
+		if decision.Authorized != (c.reason == fack.ReasonAuthorized) {
+			t.Errorf("%s: authorized flag does not match the reason", c.name)
+		}
+	}
+
+	policy, _ := fack.ParsePolicy(strings.NewReader("deny GET /export when endpoint is reports"))
+	auth.SetPolicy(policy)
+
+	fack.Sign(signed, privateKey)
+	decision := auth.Authorize(sender, signed, "/export", fack.GET)
+	if (decision.Reason != fack.ReasonPolicyDenied) || !strings.Contains(decision.Rule, "line 1") {
+		t.Errorf("policy denial was not explained by its rule: %s", decision.String())
+	}
+}
+
+func TestExplainDryRun(t *testing.T) {
+	auth := fack.NewAuth()
+	endpoint := fack.NewEndpoint("reports", nil)
+	endpoint.AddLocalPermission("/export", fack.NewPermission().Enable(fack.GET))
+	auth.AddTrusted("127.0.0.1", endpoint)
+
+	decision := auth.Explain("reports", "/export", fack.GET)
+	if !decision.Authorized || !decision.DryRun || !strings.HasPrefix(decision.Rule, "local permission") {
+		t.Errorf("dry run did not authorize through the local permission: %s", decision.String())
+	}
+
+	decision = auth.Explain("127.0.0.1", "/export", fack.DELETE)
+	if decision.Reason != fack.ReasonMethodNotPermitted {
+		t.Errorf("dry run by host did not reject the method: %s", decision.String())
+	}
+
+	if auth.Explain("unknown", "/export", fack.GET).Reason != fack.ReasonUnknownSource {
+		t.Error("dry run of an unregistered endpoint was not rejected")
+	}
+}
+
+func TestDecisionReasonNames(t *testing.T) {
+	if fack.ReasonDevelopmentBypass.ToString() != "development_bypass" {
+		t.Error("development bypass was not named")
+	}
+	if name := fack.DecisionReason(255).ToString(); name != "unknown" {
+		t.Errorf("an unknown reason was reported as %s", name)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const (
+	DevelopmentPolicyPort = 8005
+)
+
+func TestDevelopmentPolicyAllowedSources(t *testing.T) {
+	policy := fack.NewDevelopmentPolicy("localhost", "10.0.0.0/8")
+
+	if !policy.IsAllowedSource(fack.EmptyAddress().SetHost("localhost")) {
+		t.Error("development policy did not allow an explicit host")
+	}
+
+	if !policy.IsAllowedSource(fack.EmptyAddress().SetHost("10.1.2.3")) {
+		t.Error("development policy did not allow a host within a CIDR block")
+	}
+
+	if policy.IsAllowedSource(fack.EmptyAddress().SetHost("127.0.0.1")) {
+		t.Error("development policy allowed a source that was never listed")
+	}
+}
+
+func TestDevelopmentPolicyRejectedInProduction(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(DevelopmentPolicyPort), fack.Production)
+
+	if err := node.Development(fack.NewDevelopmentPolicy("127.0.0.1")); err == nil {
+		t.Error("node accepted a development policy in a production environment")
+	}
+
+	node = rpc.NewNode(fack.LocalHost().SetPort(DevelopmentPolicyPort), fack.NewDevelopmentPolicy("127.0.0.1"))
+	node.Environment(fack.Production)
+	if !node.IsProduction() {
+		t.Error("node did not switch into the production environment")
+	}
+}
+
+// an unsigned request from an allowed source should reach an authenticated route
+// without the endpoint ever being registered with the auth database
+func TestDevelopmentPolicyBypassesAuth(t *testing.T) {
+	node := rpc.NewNode(fack.LocalHost().SetPort(DevelopmentPolicyPort))
+	if err := node.Development(fack.NewDevelopmentPolicy("127.0.0.1")); err != nil {
+		t.Skip("binary was built for production")
+	}
+	node.Function("/", AuthenticatedIndex).Method(fack.GET).Auth(true)
+
+	go node.Start()
+
+	// if you are on macos, you may need to give the binary permission to use a socket port
+	time.Sleep(WaitForServerStart)
+
+	resp, err := rpc.NewRequest("/").Send("GET", LocalHost+fmt.Sprint(DevelopmentPolicyPort))
+	if err != nil {
+		t.Error("Failed to startup an HTTP GET route.")
+		return
+	}
+
+	if resp.GetStatus() != http.StatusOK {
+		t.Error("node did not apply the development policy to an allowed source")
+	}
+}
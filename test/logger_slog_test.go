@@ -0,0 +1,25 @@
+//go:build go1.21
+
+package main
+
+import (
+	"bytes"
+	"github.com/GabeCordo/fack"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLoggerAdapter(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	var logger fack.Logger = fack.NewSlogLogger(slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	if logger.Enabled(fack.LevelInfo) || !logger.Enabled(fack.LevelError) {
+		t.Error("adapter did not use the level of the slog handler")
+	}
+
+	logger.Log(fack.LevelError, "function failed", "path", "/users", "status", 500)
+	if output := buffer.String(); !strings.Contains(output, `"level":"ERROR"`) || !strings.Contains(output, `"path":"/users"`) || !strings.Contains(output, `"status":500`) {
+		t.Errorf("fields were not passed to slog: %s", output)
+	}
+}
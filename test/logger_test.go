@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"github.com/GabeCordo/fack"
+	"strings"
+	"testing"
+)
+
+func TestTextLoggerLevelsAndFields(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := fack.NewTextLogger(buffer, fack.LevelInfo)
+
+	if logger.Enabled(fack.LevelDebug) || !logger.Enabled(fack.LevelWarn) {
+		t.Error("logger did not filter by its level")
+	}
+
+	logger.Log(fack.LevelDebug, "hidden")
+	logger.Log(fack.LevelWarn, "denied", "path", "/admin", "reason", "unknown source", "status", 401, "dangling")
+
+	output := buffer.String()
+	if strings.Contains(output, "hidden") {
+		t.Error("entry below the level was written")
+	}
+	for _, expected := range []string{" WARN denied ", "path=/admin", `reason="unknown source"`, "status=401", "dangling=!MISSING"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("entry is missing %s: %s", expected, output)
+		}
+	}
+}
+
+func TestRouteDebugLogLevel(t *testing.T) {
+	route := fack.NewRoute("/")
+	if route.LogLevel() != fack.LevelDebug {
+		t.Error("route entries are not logged at the debug level by default")
+	}
+
+	route.Debug = true
+	if route.LogLevel() != fack.LevelInfo {
+		t.Error("debug route entries are not raised to the info level")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"github.com/GabeCordo/fack"
+	"github.com/GabeCordo/fack/rpc"
+	"testing"
+)
+
+func TestMultiSignatureThreshold(t *testing.T) {
+	auth := fack.NewAuth()
+	keys := make(map[string]*ecdsa.PrivateKey)
+
+	for i, name := range []string{"alice", "bob", "carol", "mallory"} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal("could not generate an ECDSA key pair")
+		}
+		keys[name] = key
+
+		endpoint := fack.NewEndpoint(name, &key.PublicKey)
+		if name != "carol" {
+			endpoint.AddLocalPermission("/deploy", fack.NewPermission().Enable(fack.POST))
+		}
+		auth.AddTrusted("10.0.0."+string(rune('1'+i)), endpoint)
+	}
+
+	route := fack.NewRoute("/deploy").Method(fack.POST).MultiSig(2, "alice", "bob", "carol")
+	requirement := route.MultiSigRequirement()
+
+	request := rpc.NewRequest("/deploy")
+	fack.CoSign(request, "alice", keys["alice"], "/deploy", fack.POST)
+
+	if decision := auth.AuthorizeMultiSig(request, "/deploy", fack.POST, requirement); decision.Authorized {
+		t.Error("a single signature satisfied a 2 of 3 requirement")
+	}
+
+	// carol has no permission to POST on /deploy and mallory is not a named signer
+	fack.CoSign(request, "carol", keys["carol"], "/deploy", fack.POST)
+	fack.CoSign(request, "mallory", keys["mallory"], "/deploy", fack.POST)
+	decision := auth.AuthorizeMultiSig(request, "/deploy", fack.POST, requirement)
+	if decision.Authorized || (decision.Reason != fack.ReasonInsufficientSignatures) {
+		t.Errorf("signatures without permission or membership were counted: %s", decision.String())
+	}
+
+	// a signature from one endpoint replayed under another signer's name must not verify
+	forged := fack.Signature{Endpoint: "bob", Nonce: request.GetSignatures()[0].Nonce, Signature: request.GetSignatures()[0].Signature}
+	replayed := rpc.NewRequest("/deploy")
+	fack.CoSign(replayed, "alice", keys["alice"], "/deploy", fack.POST)
+	replayed.AddSignature(forged)
+	if auth.AuthorizeMultiSig(replayed, "/deploy", fack.POST, requirement).Authorized {
+		t.Error("a signature was accepted for an endpoint that did not produce it")
+	}
+
+	// the signature of alice was verified (and its nonce recorded) by the first attempt
+	fack.CoSign(request, "alice", keys["alice"], "/deploy", fack.POST)
+	fack.CoSign(request, "bob", keys["bob"], "/deploy", fack.POST)
+	decision = auth.AuthorizeMultiSig(request, "/deploy", fack.POST, requirement)
+	if !decision.Authorized || (decision.Endpoint != "alice,bob") {
+		t.Errorf("two valid signatures did not satisfy a 2 of 3 requirement: %s", decision.String())
+	}
+
+	if decision = auth.AuthorizeMultiSig(request, "/deploy", fack.POST, requirement); decision.Authorized {
+		t.Errorf("replayed co-signatures were accepted: %s", decision.String())
+	}
+
+	// a co-signature is bound to the route and method it was produced for
+	signature := request.GetSignatures()[0]
+	hash := fack.CoSignatureHash(request, signature.Nonce, "/rollback", fack.POST)
+	if ecdsa.VerifyASN1(&keys["alice"].PublicKey, hash, signature.Signature) {
+		t.Error("a co-signature for /deploy verified for another route")
+	}
+	hash = fack.CoSignatureHash(request, signature.Nonce, "/deploy", fack.DELETE)
+	if ecdsa.VerifyASN1(&keys["alice"].PublicKey, hash, signature.Signature) {
+		t.Error("a co-signature for POST verified for another method")
+	}
+
+	if auth.AuthorizeMultiSig(request, "/deploy", fack.DELETE, requirement).Authorized {
+		t.Error("signers were authorized for a method they have no permission for")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/GabeCordo/fack"
+	"strings"
+	"testing"
+	"time"
+)
+
+const examplePolicy = `
+# reports may only export during the nightly window
+allow POST /deploy when endpoint matches ci-*
+deny  POST /deploy
+deny  *    /export when role is reports and not time in 01:00-05:00
+deny  GET  /users/* when source in 10.0.0.0/8
+deny  GET  /search when param[0] is "drop tables"
+`
+
+func evaluate(policy *fack.Policy, context fack.PolicyContext) bool {
+	if context.Source == nil {
+		context.Source = fack.LocalHost()
+	}
+	allowed, _ := policy.Evaluate(context)
+	return allowed
+}
+
+func TestPolicyParseAndEvaluate(t *testing.T) {
+	policy, err := fack.ParsePolicy(strings.NewReader(examplePolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ci := fack.NewEndpoint("ci-runner", nil)
+	dev := fack.NewEndpoint("laptop", nil)
+	reports := fack.NewEndpoint("reports", nil)
+	reports.AddRole("reports")
+
+	if !evaluate(policy, fack.PolicyContext{Endpoint: ci, Path: "/deploy", Method: fack.POST}) {
+		t.Error("ci endpoint should be allowed to POST to /deploy")
+	}
+	if evaluate(policy, fack.PolicyContext{Endpoint: dev, Path: "/deploy", Method: fack.POST}) {
+		t.Error("non-ci endpoint should not be allowed to POST to /deploy")
+	}
+
+	night := time.Date(2023, 1, 1, 2, 30, 0, 0, time.UTC)
+	noon := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	if !evaluate(policy, fack.PolicyContext{Endpoint: reports, Path: "/export", Method: fack.GET, Time: night}) {
+		t.Error("reports endpoint should be allowed to export within the window")
+	}
+	if evaluate(policy, fack.PolicyContext{Endpoint: reports, Path: "/export", Method: fack.GET, Time: noon}) {
+		t.Error("reports endpoint should not be allowed to export outside of the window")
+	}
+	if !evaluate(policy, fack.PolicyContext{Endpoint: dev, Path: "/export", Method: fack.GET, Time: noon}) {
+		t.Error("endpoints without the reports role should not be restricted")
+	}
+
+	internal := fack.EmptyAddress().SetHost("10.4.0.1")
+	if evaluate(policy, fack.PolicyContext{Endpoint: dev, Path: "/users/42", Method: fack.GET, Source: internal}) {
+		t.Error("source within the CIDR block should be denied")
+	}
+
+	params := []string{"drop tables"}
+	if evaluate(policy, fack.PolicyContext{Endpoint: dev, Path: "/search", Method: fack.GET, Params: params}) {
+		t.Error("quoted parameter value should be denied")
+	}
+}
+
+func TestPolicyDefaultAndErrors(t *testing.T) {
+	policy, err := fack.ParsePolicy(strings.NewReader("default deny\nallow GET /"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed, rule := policy.Evaluate(fack.PolicyContext{Path: "/other", Method: fack.GET})
+	if allowed || (rule != nil) {
+		t.Error("unmatched request should fall through to the default deny")
+	}
+
+	malformed := []string{
+		"permit GET /",
+		"allow FETCH /",
+		"allow GET / when colour is blue",
+		"allow GET / when time is 01:00",
+		"allow GET / when time in 1am-5am",
+		"allow GET / when source in 10.0.0.0/33",
+		"allow GET / when role is a or role is b",
+	}
+	for _, text := range malformed {
+		if _, err := fack.ParsePolicy(strings.NewReader(text)); err == nil {
+			t.Errorf("malformed policy %q was accepted", text)
+		}
+	}
+}
@@ -1,22 +1,74 @@
 package fack
 
+import "context"
+
 type Router func(request Request, response Response)
 
+// ContextRouter
+// A Router that receives the context of the request, handlers should return once the context
+// is done as their response will no longer be sent.
+type ContextRouter func(ctx context.Context, request Request, response Response)
+
+// Adapt
+// Allows a Router to be used wherever a ContextRouter is expected.
+func Adapt(router Router) ContextRouter {
+	return func(ctx context.Context, request Request, response Response) {
+		router(request, response)
+	}
+}
+
+// Middleware
+// Wraps a Router to inspect the request and response before or after calling next, a
+// middleware that does not call next short-circuits the chain.
+type Middleware func(next Router) Router
+
 type Node interface {
-	Start()
-	Shutdown()
-	AddFunction(path string, handler Router) Route
+	Start() error
+	Shutdown() error
+	AddFunction(route *Route, handler ContextRouter) error
+	RemoveFunction(path string) error
 }
 
 type Request interface {
+	Context() context.Context
+	RequestID() string
 	GetEndpoint() string
+	GetParams() []string
+	PathParam(name string) string
+	Arg(index int) Argument
+	NamedArg(name string) Argument
+	ArgCount() int
+	DecodeArgs(v any) error
 	GetSignature() []byte
 	SetSignature(bytes []byte)
+	GetSignatures() []Signature
+	AddSignature(signature Signature)
 	GetHash() []byte
+	GetHashWithNonce(nonce int64) []byte
 	GetNonce() int64
 	SetNonce(nonce int64)
 }
 
+// LegacyRequest
+// Implemented by requests that may also be signed with the hash of a client that predates
+// structured arguments, which only covers the function and the nonce. The hash is only returned
+// when the node accepts such signatures for the request.
+type LegacyRequest interface {
+	GetLegacyHash() ([]byte, bool)
+}
+
+// Argument
+// A structured request argument. The typed accessors return an error if the argument does not
+// exist or cannot be represented as the type requested.
+type Argument interface {
+	Exists() bool
+	String() (string, error)
+	Int() (int64, error)
+	Float() (float64, error)
+	Bool() (bool, error)
+	Decode(v any) error
+}
+
 type ResponseData map[string]any
 
 type Response interface {
@@ -3,7 +3,10 @@ package fack
 import (
 	"bytes"
 	"math/rand"
+	"mime"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -34,13 +37,27 @@ func GenerateRandomString(seed int) string {
 	buffer := new(bytes.Buffer)
 	for i := 0; i < maxGeneratedStringLength; i++ {
 		char := RandInteger(lowerASCIIBound, upperASCIIBound)
-		buffer.WriteString(string(char))
+		buffer.WriteRune(rune(char))
 	}
 	return buffer.String()
 }
 
+var lastNonce int64
+
+// GenerateNonce
+// Nonces only ever increase, a Node rejects a nonce that is not newer than the last it accepted
+// from the endpoint. The clock is used so that nonces keep increasing across restarts.
 func GenerateNonce() int64 {
-	return time.Now().Unix() * RandInteger64(4, 9)
+	for {
+		last := atomic.LoadInt64(&lastNonce)
+		nonce := time.Now().UnixNano()
+		if nonce <= last {
+			nonce = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&lastNonce, last, nonce) {
+			return nonce
+		}
+	}
 }
 
 func GetInternetProtocol(r *http.Request) (*Address, error) {
@@ -51,7 +68,17 @@ func GetInternetProtocol(r *http.Request) (*Address, error) {
 	return NewAddress(r.RemoteAddr)
 }
 
+// IsUsingJSONContent
+// Parses the Content-Type header as a media type, parameters such as charset=utf-8 are accepted
+// as long as the charset (when given) is UTF-8, the only encoding JSON is exchanged in.
 func IsUsingJSONContent(r *http.Request) bool {
-	content := r.Header.Get("Content-Type")
-	return content == "application/json"
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if (err != nil) || (mediaType != "application/json") {
+		return false
+	}
+
+	if charset, found := params["charset"]; found && !strings.EqualFold(charset, "utf-8") {
+		return false
+	}
+	return true
 }